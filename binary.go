@@ -0,0 +1,210 @@
+package fs_utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// ErrBinaryFile is matched by BinaryFileError.
+var ErrBinaryFile = errors.New("file is binary")
+
+// ContentKind is a kind of file content detected by DetectContentKind.
+type ContentKind string
+
+const (
+	ContentEmpty  ContentKind = "empty"
+	ContentText   ContentKind = "text"
+	ContentBinary ContentKind = "binary"
+	ContentPNG    ContentKind = "png"
+	ContentJPEG   ContentKind = "jpeg"
+	ContentGIF    ContentKind = "gif"
+	ContentPDF    ContentKind = "pdf"
+	ContentZIP    ContentKind = "zip"
+	ContentGzip   ContentKind = "gzip"
+	ContentELF    ContentKind = "elf"
+	ContentPE     ContentKind = "pe"
+	ContentMachO  ContentKind = "mach-o"
+	ContentWasm   ContentKind = "wasm"
+)
+
+// contentMagics are leading bytes of known binary formats.
+var contentMagics = []struct {
+	magic []byte
+	kind  ContentKind
+}{
+	{[]byte("\x89PNG\r\n\x1a\n"), ContentPNG},
+	{[]byte("\xff\xd8\xff"), ContentJPEG},
+	{[]byte("GIF87a"), ContentGIF},
+	{[]byte("GIF89a"), ContentGIF},
+	{[]byte("%PDF-"), ContentPDF},
+	{[]byte("PK\x03\x04"), ContentZIP},
+	{[]byte("PK\x05\x06"), ContentZIP},
+	{[]byte("\x1f\x8b"), ContentGzip},
+	{[]byte("\x7fELF"), ContentELF},
+	{[]byte("\xfe\xed\xfa\xce"), ContentMachO},
+	{[]byte("\xfe\xed\xfa\xcf"), ContentMachO},
+	{[]byte("\xce\xfa\xed\xfe"), ContentMachO},
+	{[]byte("\xcf\xfa\xed\xfe"), ContentMachO},
+	{[]byte("\x00asm"), ContentWasm},
+}
+
+// binarySniffLen is how many leading bytes are checked
+// when detecting binary files, same as git checks.
+const binarySniffLen = 8000
+
+// maxInvalidRatio is maximal share of invalid UTF-8 bytes in text.
+const maxInvalidRatio = 0.1
+
+// IsBinary reports whether content kind isn't text.
+func (k ContentKind) IsBinary() bool {
+	return k != ContentText && k != ContentEmpty
+}
+
+// DetectContentKind guesses kind of content by its leading bytes.
+// Known magic numbers are checked first. Then content is binary,
+// if it contains NUL byte or too many invalid UTF-8 sequences.
+func DetectContentKind(data []byte) ContentKind {
+	if len(data) == 0 {
+		return ContentEmpty
+	}
+	if len(data) > binarySniffLen {
+		data = data[:binarySniffLen]
+	}
+
+	for _, m := range contentMagics {
+		if bytes.HasPrefix(data, m.magic) {
+			return m.kind
+		}
+	}
+
+	if isPE(data) {
+		return ContentPE
+	}
+
+	if bytes.IndexByte(data, 0) >= 0 {
+		return ContentBinary
+	}
+
+	invalid := 0
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			// Rune can be cut at the end of sniffed data.
+			if !utf8.FullRune(data[i:]) {
+				break
+			}
+			invalid++
+		}
+		i += size
+	}
+	if float64(invalid) > float64(len(data))*maxInvalidRatio {
+		return ContentBinary
+	}
+
+	return ContentText
+}
+
+// isPE reports whether data is Windows executable.
+// "MZ" alone is too short to be checked,
+// so offset of PE header is followed.
+func isPE(data []byte) bool {
+	if !bytes.HasPrefix(data, []byte("MZ")) || len(data) < 0x40 {
+		return false
+	}
+
+	// Offset is kept unsigned, int can overflow on 32-bit systems.
+	offset := uint64(binary.LittleEndian.Uint32(data[0x3c:]))
+	if offset > uint64(len(data))-4 {
+		return false
+	}
+	return bytes.Equal(data[offset:offset+4], []byte("PE\x00\x00"))
+}
+
+// DetectFileKind guesses kind of file content by its leading bytes.
+func DetectFileKind(path string) (ContentKind, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	data := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(file, data)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	return DetectContentKind(data[:n]), nil
+}
+
+// IsBinary reports whether file at path looks binary.
+func IsBinary(path string) (bool, error) {
+	kind, err := DetectFileKind(path)
+	if err != nil {
+		return false, err
+	}
+	return kind.IsBinary(), nil
+}
+
+// BinaryFileError is returned when text is read from binary file.
+type BinaryFileError struct {
+	Path string
+	Kind ContentKind
+}
+
+func (e *BinaryFileError) Error() string {
+	return fmt.Sprintf("file %v is binary (%v)", e.Path, e.Kind)
+}
+
+// Is makes errors.Is(err, ErrBinaryFile) true.
+func (e *BinaryFileError) Is(target error) bool {
+	return target == ErrBinaryFile
+}
+
+// checkText returns *BinaryFileError, if file at path looks binary.
+func checkText(path string) error {
+	kind, err := DetectFileKind(path)
+	if err != nil {
+		return err
+	}
+	if kind.IsBinary() {
+		return &BinaryFileError{path, kind}
+	}
+	return nil
+}
+
+// sniffText reads leading bytes of r, which is content of file at path.
+// If they look binary, returns *BinaryFileError.
+// Otherwise returns reader of whole content of r.
+func sniffText(path string, r io.Reader) (io.Reader, error) {
+	head := make([]byte, binarySniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if kind := DetectContentKind(head[:n]); kind.IsBinary() {
+		return nil, &BinaryFileError{path, kind}
+	}
+	return io.MultiReader(bytes.NewReader(head[:n]), r), nil
+}
+
+// GetTextFileContent works same as GetFileContent,
+// but returns *BinaryFileError if file looks binary.
+func GetTextFileContent(path string) (FileLines, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	text, err := sniffText(path, file)
+	if err != nil {
+		return nil, err
+	}
+	return scanLines(text)
+}
@@ -0,0 +1,74 @@
+package fs_utils
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectContentKind(t *testing.T) {
+	pe := make([]byte, 0x80)
+	copy(pe, "MZ")
+	pe[0x3c] = 0x40
+	copy(pe[0x40:], "PE\x00\x00")
+	// e_lfanew far beyond the data
+	huge := make([]byte, 0x80)
+	copy(huge, "MZ")
+	copy(huge[0x3c:], "\xfc\xff\xff\xff")
+
+	tests := []struct {
+		data     []byte
+		expected ContentKind
+	}{
+		{nil, ContentEmpty},
+		{[]byte("hello\nworld\n"), ContentText},
+		{[]byte("привет, мир\n"), ContentText},
+		{[]byte("MZ is a text line\n"), ContentText},
+		{[]byte("\x89PNG\r\n\x1a\n\x00\x00"), ContentPNG},
+		{[]byte("\x7fELF\x02\x01\x01"), ContentELF},
+		{[]byte("%PDF-1.7\n"), ContentPDF},
+		{pe, ContentPE},
+		{huge, ContentBinary},
+		{[]byte("text\x00with nul"), ContentBinary},
+		{[]byte("\xff\xfe\xfd\xfc\xfb\xfa text"), ContentBinary},
+	}
+
+	for _, tt := range tests {
+		if kind := DetectContentKind(tt.data); kind != tt.expected {
+			t.Errorf("expected %q to be %v, got: %v", tt.data, tt.expected, kind)
+		}
+	}
+
+	// Test rune cut at the end of sniffed data
+	data := append(bytes.Repeat([]byte("a"), binarySniffLen-1), "я"...)
+	if kind := DetectContentKind(data); kind != ContentText {
+		t.Errorf("expected cut rune to be ignored, got: %v", kind)
+	}
+}
+
+func TestGetTextFileContent(t *testing.T) {
+	tempDir := t.TempDir()
+	text := filepath.Join(tempDir, "test.txt")
+	image := filepath.Join(tempDir, "test.png")
+	os.WriteFile(text, []byte("test\n"), 0644)
+	os.WriteFile(image, []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"), 0644)
+
+	if lines, err := GetTextFileContent(text); err != nil || len(lines) != 1 {
+		t.Errorf("expected to read text file, got: %v, error: %v", lines, err)
+	}
+
+	_, err := GetTextFileContent(image)
+	var binaryErr *BinaryFileError
+	if !errors.As(err, &binaryErr) || binaryErr.Kind != ContentPNG || !errors.Is(err, ErrBinaryFile) {
+		t.Errorf("expected BinaryFileError, got: %v", err)
+	}
+
+	if binary, err := IsBinary(image); err != nil || !binary {
+		t.Errorf("expected image to be binary, error: %v", err)
+	}
+	if err := FprintFileContent(&bytes.Buffer{}, image, DefaultLineFormat); !errors.Is(err, ErrBinaryFile) {
+		t.Errorf("expected FprintFileContent to refuse binary file, got: %v", err)
+	}
+}
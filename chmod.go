@@ -0,0 +1,273 @@
+package fs_utils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Unix permission bits, including setuid, setgid and sticky ones.
+const (
+	modeSetuid = 04000
+	modeSetgid = 02000
+	modeSticky = 01000
+
+	modeUser  = 0700
+	modeGroup = 0070
+	modeOther = 0007
+)
+
+// ChmodOptions configures ChmodR.
+// Modes are numeric ("755") or symbolic ("u+rwx,go-w", "a+X").
+// Empty mode means that entries of that type aren't changed.
+type ChmodOptions struct {
+	FileMode string
+	DirMode  string
+	// DryRun reports what would be changed without touching disk.
+	DryRun bool
+}
+
+// ParseMode applies mode spec to current mode and returns new mode.
+// Spec is numeric like "0755", or symbolic like chmod(1) accepts:
+// comma-separated clauses of who (u, g, o, a), operator (+, -, =)
+// and permissions (r, w, x, X, s, t) or copy of other class (u, g, o).
+// If who is omitted, "a" is used.
+// X sets execute bit only for directories and files
+// which already have execute bit for someone.
+// If spec is invalid, returns an error.
+func ParseMode(spec string, current os.FileMode, isDir bool) (os.FileMode, error) {
+	if spec == "" {
+		return 0, fmt.Errorf("empty mode")
+	}
+
+	if isOctal(spec) {
+		if len(spec) > 4 {
+			return 0, fmt.Errorf("invalid mode %v", spec)
+		}
+		bits, _ := strconv.ParseUint(spec, 8, 32)
+		return fromUnixMode(current, uint32(bits)), nil
+	}
+
+	bits := toUnixMode(current)
+	for _, clause := range strings.Split(spec, ",") {
+		var err error
+		if bits, err = applyModeClause(clause, bits, isDir); err != nil {
+			return 0, fmt.Errorf("invalid mode %v: %w", spec, err)
+		}
+	}
+
+	return fromUnixMode(current, bits), nil
+}
+
+// isOctal reports whether s consists of octal digits only.
+func isOctal(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '7' {
+			return false
+		}
+	}
+	return true
+}
+
+// applyModeClause applies single symbolic clause like "go-w" to bits.
+func applyModeClause(clause string, bits uint32, isDir bool) (uint32, error) {
+	i := 0
+	var who uint32
+	for ; i < len(clause) && strings.IndexByte("ugoa", clause[i]) >= 0; i++ {
+		switch clause[i] {
+		case 'u':
+			who |= modeUser | modeSetuid
+		case 'g':
+			who |= modeGroup | modeSetgid
+		case 'o':
+			who |= modeOther | modeSticky
+		case 'a':
+			who |= modeUser | modeGroup | modeOther | modeSetuid | modeSetgid | modeSticky
+		}
+	}
+
+	if who == 0 {
+		who = modeUser | modeGroup | modeOther | modeSetuid | modeSetgid | modeSticky
+	}
+	if i == len(clause) {
+		return 0, fmt.Errorf("missing operator in %q", clause)
+	}
+
+	for i < len(clause) {
+		op := clause[i]
+		if op != '+' && op != '-' && op != '=' {
+			return 0, fmt.Errorf("unexpected %q in %q", op, clause)
+		}
+		i++
+
+		var perm uint32
+		for ; i < len(clause) && strings.IndexByte("+-=", clause[i]) < 0; i++ {
+			switch clause[i] {
+			case 'r':
+				perm |= 0444
+			case 'w':
+				perm |= 0222
+			case 'x':
+				perm |= 0111
+			case 'X':
+				if isDir || bits&0111 != 0 {
+					perm |= 0111
+				}
+			case 's':
+				perm |= modeSetuid | modeSetgid
+			case 't':
+				perm |= modeSticky
+			case 'u':
+				perm |= copyModeClass((bits & modeUser) >> 6)
+			case 'g':
+				perm |= copyModeClass((bits & modeGroup) >> 3)
+			case 'o':
+				perm |= copyModeClass(bits & modeOther)
+			default:
+				return 0, fmt.Errorf("unknown permission %q in %q", clause[i], clause)
+			}
+		}
+
+		perm &= who
+		switch op {
+		case '+':
+			bits |= perm
+		case '-':
+			bits &^= perm
+		case '=':
+			bits = bits&^who | perm
+		}
+	}
+
+	return bits, nil
+}
+
+// copyModeClass spreads rwx bits of one class to all classes.
+func copyModeClass(class uint32) uint32 {
+	return class<<6 | class<<3 | class
+}
+
+// toUnixMode converts os.FileMode to Unix permission bits.
+func toUnixMode(mode os.FileMode) uint32 {
+	bits := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		bits |= modeSetuid
+	}
+	if mode&os.ModeSetgid != 0 {
+		bits |= modeSetgid
+	}
+	if mode&os.ModeSticky != 0 {
+		bits |= modeSticky
+	}
+	return bits
+}
+
+// fromUnixMode replaces permission bits of mode with Unix bits.
+// File type bits of mode are kept.
+func fromUnixMode(mode os.FileMode, bits uint32) os.FileMode {
+	result := mode.Type() | os.FileMode(bits&0777)
+	if bits&modeSetuid != 0 {
+		result |= os.ModeSetuid
+	}
+	if bits&modeSetgid != 0 {
+		result |= os.ModeSetgid
+	}
+	if bits&modeSticky != 0 {
+		result |= os.ModeSticky
+	}
+	return result
+}
+
+// chmodBits returns mode which os.Chmod accepts.
+func chmodBits(mode os.FileMode) os.FileMode {
+	return mode & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+}
+
+// Chmod changes mode of file or directory.
+// Mode is numeric or symbolic, see ParseMode.
+func Chmod(path, mode string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	newMode, err := ParseMode(mode, info.Mode(), info.IsDir())
+	if err != nil {
+		return err
+	}
+
+	return os.Chmod(path, chmodBits(newMode))
+}
+
+// ChmodR changes modes of files and directories under root, including root.
+// Files and directories get separate modes from opts.
+// Symlinks are skipped.
+// Returns actions which were taken, or would be taken if opts.DryRun is set.
+// Entries which already have the mode aren't listed.
+func ChmodR(root string, opts ChmodOptions) (Plan, error) {
+	var plan Plan
+	err := filepath.Walk(root, func(location string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		spec := opts.FileMode
+		if info.IsDir() {
+			spec = opts.DirMode
+		}
+		if spec == "" || info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+
+		newMode, err := ParseMode(spec, info.Mode(), info.IsDir())
+		if err != nil {
+			return err
+		}
+		if newMode == info.Mode() {
+			return nil
+		}
+
+		plan = append(plan, PlanAction{Op: PlanChmod, Path: location, Mode: newMode})
+		if opts.DryRun {
+			return nil
+		}
+		return os.Chmod(location, chmodBits(newMode))
+	})
+
+	return plan, err
+}
+
+// Chown changes owner and group of file or directory.
+// If uid or gid is -1, then it isn't changed.
+func Chown(path string, uid, gid int) error {
+	return os.Chown(path, uid, gid)
+}
+
+// ChownR changes owner and group of files and directories under root,
+// including root. Symlinks themselves are changed, not their targets.
+// If uid or gid is -1, then it isn't changed.
+// Returns actions which were taken, or would be taken if dryRun is set.
+// Entries which already have owner and group are skipped.
+func ChownR(root string, uid, gid int, dryRun bool) (Plan, error) {
+	var plan Plan
+	err := filepath.Walk(root, func(location string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if oldUID, oldGID, ok := fileOwner(info); ok && (uid == -1 || uid == oldUID) && (gid == -1 || gid == oldGID) {
+			return nil
+		}
+
+		plan = append(plan, PlanAction{Op: PlanChown, Path: location, UID: uid, GID: gid})
+		if dryRun {
+			return nil
+		}
+		return os.Lchown(location, uid, gid)
+	})
+
+	return plan, err
+}
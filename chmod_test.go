@@ -0,0 +1,108 @@
+package fs_utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		spec    string
+		current os.FileMode
+		isDir   bool
+		want    os.FileMode
+	}{
+		{"755", 0644, false, 0755},
+		{"0600", 0777, false, 0600},
+		{"u+x", 0644, false, 0744},
+		{"go-w", 0666, false, 0644},
+		{"a=r", 0777, false, 0444},
+		{"u+rwx,go-w", 0666, false, 0744},
+		{"a+X", 0644, false, 0644},
+		{"a+X", 0744, false, 0755},
+		{"a+X", 0644 | os.ModeDir, true, 0755 | os.ModeDir},
+		{"+x", 0600, false, 0711},
+		{"g=u", 0640, false, 0660},
+		{"u+s", 0755, false, 0755 | os.ModeSetuid},
+		{"+t", 0777 | os.ModeDir, true, 0777 | os.ModeDir | os.ModeSticky},
+		{"u-w+x", 0644, false, 0544},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMode(tt.spec, tt.current, tt.isDir)
+		if err != nil {
+			t.Errorf("expected to parse mode %q, error: %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expected mode %q applied to %v to be: %v, got: %v", tt.spec, tt.current, tt.want, got)
+		}
+	}
+}
+
+func TestParseModeInvalid(t *testing.T) {
+	for _, spec := range []string{"", "u", "u+q", "88", "77777", "u+r,"} {
+		if _, err := ParseMode(spec, 0644, false); err == nil {
+			t.Errorf("expected error for mode %q", spec)
+		}
+	}
+}
+
+func TestChmodR(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits aren't supported on windows")
+	}
+
+	tempDir := t.TempDir()
+	subdir := filepath.Join(tempDir, "subdir")
+	file := filepath.Join(subdir, "test.txt")
+	os.Mkdir(subdir, 0755)
+	os.WriteFile(file, []byte("test"), 0644)
+	os.Chmod(tempDir, 0755)
+
+	opts := ChmodOptions{FileMode: "go-r", DirMode: "700", DryRun: true}
+
+	// Test dry run
+	plan, err := ChmodR(tempDir, opts)
+	if err != nil {
+		t.Fatalf("expected to plan chmod: %v, error: %v", tempDir, err)
+	}
+	if len(plan) != 3 {
+		t.Errorf("expected 3 actions, got: %v", plan)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0644 {
+		t.Errorf("expected dry run to leave mode untouched, got: %v", info.Mode())
+	}
+
+	// Test applying modes
+	opts.DryRun = false
+	if _, err := ChmodR(tempDir, opts); err != nil {
+		t.Fatalf("expected to chmod: %v, error: %v", tempDir, err)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0600 {
+		t.Errorf("expected file mode to be 0600, got: %v", info.Mode())
+	}
+	if info, _ := os.Stat(subdir); info.Mode().Perm() != 0700 {
+		t.Errorf("expected directory mode to be 0700, got: %v", info.Mode())
+	}
+}
+
+func TestChownRUnchanged(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("owners aren't supported on windows")
+	}
+
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("test"), 0644)
+
+	// Test entries which already have owner and group
+	plan, err := ChownR(tempDir, os.Getuid(), os.Getgid(), true)
+	if err != nil {
+		t.Fatalf("expected to plan chown: %v, error: %v", tempDir, err)
+	}
+	if len(plan) != 0 {
+		t.Errorf("expected no actions, got: %v", plan)
+	}
+}
@@ -0,0 +1,474 @@
+package fs_utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DiffOp is a kind of edit in EditScript.
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffDelete
+	DiffInsert
+)
+
+// DiffEdit is single line of EditScript.
+// OldLine and NewLine are 0-based positions in old and new lines.
+// For deleted line NewLine is position in new lines where it was removed,
+// for inserted line OldLine is position in old lines where it was added.
+type DiffEdit struct {
+	Op      DiffOp
+	OldLine int
+	NewLine int
+	OldText string
+	NewText string
+	// Ignored marks changes of lines skipped by DiffOptions.
+	// They are kept, so line numbers of script match real lines,
+	// but they aren't counted as changes.
+	Ignored bool
+}
+
+// EditScript is a list of edits, which turns old lines into new ones.
+// Should be initialized by Diff or DiffFiles.
+type EditScript []DiffEdit
+
+// DiffOptions configures Diff.
+type DiffOptions struct {
+	// IgnoreWhitespace compares lines without any whitespace.
+	IgnoreWhitespace bool
+	// IgnoreBlankLines skips blank lines when comparing,
+	// so changes of them are marked as Ignored in EditScript.
+	IgnoreBlankLines bool
+}
+
+// Diff compares old and new lines with Myers algorithm
+// and returns shortest EditScript between them.
+// In every group of changes, deletions go before insertions.
+func Diff(old, new FileLines, opts DiffOptions) EditScript {
+	oldIndexes, newIndexes := diffLines(old, opts), diffLines(new, opts)
+	oldKeys, newKeys := diffKeys(old, oldIndexes, new, newIndexes, opts)
+
+	var script EditScript
+	oldLine, newLine := 0, 0
+	// skipped adds lines, which weren't compared, up to oldEnd and newEnd.
+	// Skipped lines are paired as equal, others are ignored changes.
+	skipped := func(oldEnd, newEnd int) {
+		for ; oldLine < oldEnd && newLine < newEnd; oldLine, newLine = oldLine+1, newLine+1 {
+			if old[oldLine] == new[newLine] || opts.IgnoreWhitespace {
+				script = append(script, DiffEdit{Op: DiffEqual, OldLine: oldLine, NewLine: newLine,
+					OldText: old[oldLine], NewText: new[newLine]})
+				continue
+			}
+			script = append(script,
+				DiffEdit{Op: DiffDelete, OldLine: oldLine, NewLine: newLine, OldText: old[oldLine], Ignored: true},
+				DiffEdit{Op: DiffInsert, OldLine: oldLine + 1, NewLine: newLine, NewText: new[newLine], Ignored: true})
+		}
+		for ; oldLine < oldEnd; oldLine++ {
+			script = append(script, DiffEdit{Op: DiffDelete, OldLine: oldLine, NewLine: newLine,
+				OldText: old[oldLine], Ignored: true})
+		}
+		for ; newLine < newEnd; newLine++ {
+			script = append(script, DiffEdit{Op: DiffInsert, OldLine: oldLine, NewLine: newLine,
+				NewText: new[newLine], Ignored: true})
+		}
+	}
+
+	i, j := 0, 0
+	for _, op := range myers(oldKeys, newKeys) {
+		switch op {
+		case DiffEqual:
+			skipped(oldIndexes[i], newIndexes[j])
+			i++
+			j++
+		case DiffDelete:
+			skipped(oldIndexes[i], newLine)
+			i++
+		case DiffInsert:
+			skipped(oldLine, newIndexes[j])
+			j++
+		}
+
+		edit := DiffEdit{Op: op, OldLine: oldLine, NewLine: newLine}
+		if op != DiffInsert {
+			edit.OldText = old[oldLine]
+			oldLine++
+		}
+		if op != DiffDelete {
+			edit.NewText = new[newLine]
+			newLine++
+		}
+		script = append(script, edit)
+	}
+	skipped(len(old), len(new))
+
+	return script
+}
+
+// DiffFiles compares content of files at oldPath and newPath.
+func DiffFiles(oldPath, newPath string, opts DiffOptions) (EditScript, error) {
+	old, err := GetFileContent(oldPath)
+	if err != nil {
+		return nil, err
+	}
+
+	new, err := GetFileContent(newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return Diff(old, new, opts), nil
+}
+
+// diffLines returns indexes of lines which are compared.
+func diffLines(lines FileLines, opts DiffOptions) []int {
+	indexes := make([]int, 0, len(lines))
+	for i, line := range lines {
+		if opts.IgnoreBlankLines && strings.TrimSpace(line) == "" {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// diffKeys maps compared lines of old and new to integers,
+// so equal lines get equal keys.
+func diffKeys(old FileLines, oldIndexes []int, new FileLines, newIndexes []int, opts DiffOptions) ([]int, []int) {
+	ids := make(map[string]int)
+	key := func(line string) int {
+		if opts.IgnoreWhitespace {
+			line = strings.Map(func(r rune) rune {
+				if unicode.IsSpace(r) {
+					return -1
+				}
+				return r
+			}, line)
+		}
+
+		id, ok := ids[line]
+		if !ok {
+			id = len(ids)
+			ids[line] = id
+		}
+		return id
+	}
+
+	oldKeys := make([]int, len(oldIndexes))
+	for i, index := range oldIndexes {
+		oldKeys[i] = key(old[index])
+	}
+	newKeys := make([]int, len(newIndexes))
+	for i, index := range newIndexes {
+		newKeys[i] = key(new[index])
+	}
+	return oldKeys, newKeys
+}
+
+// myers returns shortest list of operations which turns a into b.
+// See "An O(ND) Difference Algorithm and Its Variations" by E. Myers.
+// Uses linear space variant, which splits sequences at middle snake.
+func myers(a, b []int) []DiffOp {
+	var ops []DiffOp
+	myersCompare(a, b, &ops)
+	return sortChanges(ops)
+}
+
+// myersCompare appends operations which turn a into b.
+func myersCompare(a, b []int, ops *[]DiffOp) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix &&
+		a[len(a)-suffix-1] == b[len(b)-suffix-1] {
+		suffix++
+	}
+
+	appendOps(ops, DiffEqual, prefix)
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		appendOps(ops, DiffInsert, len(b))
+	case len(b) == 0:
+		appendOps(ops, DiffDelete, len(a))
+	default:
+		x, y, ok := middleSnake(a, b)
+		if !ok {
+			appendOps(ops, DiffDelete, len(a))
+			appendOps(ops, DiffInsert, len(b))
+			break
+		}
+		myersCompare(a[:x], b[:y], ops)
+		myersCompare(a[x:], b[y:], ops)
+	}
+
+	appendOps(ops, DiffEqual, suffix)
+}
+
+// appendOps appends n operations op.
+func appendOps(ops *[]DiffOp, op DiffOp, n int) {
+	for i := 0; i < n; i++ {
+		*ops = append(*ops, op)
+	}
+}
+
+// middleSnake searches paths from both ends of a and b at once,
+// until they overlap. Returns point, where sequences can be split.
+// Sequences shouldn't have common prefix or suffix.
+func middleSnake(a, b []int) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+
+	delta := n - m
+	// If delta is odd, paths overlap while going forward.
+	front := delta%2 != 0
+	k1start, k1end, k2start, k2end := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k1 := -d + k1start; k1 <= d-k1end; k1 += 2 {
+			i := offset + k1
+			var x1 int
+			if k1 == -d || (k1 != d && forward[i-1] < forward[i+1]) {
+				x1 = forward[i+1]
+			} else {
+				x1 = forward[i-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			forward[i] = x1
+
+			switch {
+			case x1 > n:
+				k1end += 2
+			case y1 > m:
+				k1start += 2
+			case front:
+				j := offset + delta - k1
+				if j >= 0 && j < len(backward) && backward[j] != -1 && x1 >= n-backward[j] {
+					return x1, y1, true
+				}
+			}
+		}
+
+		for k2 := -d + k2start; k2 <= d-k2end; k2 += 2 {
+			i := offset + k2
+			var x2 int
+			if k2 == -d || (k2 != d && backward[i-1] < backward[i+1]) {
+				x2 = backward[i+1]
+			} else {
+				x2 = backward[i-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+				x2++
+				y2++
+			}
+			backward[i] = x2
+
+			switch {
+			case x2 > n:
+				k2end += 2
+			case y2 > m:
+				k2start += 2
+			case !front:
+				j := offset + delta - k2
+				if j >= 0 && j < len(forward) && forward[j] != -1 {
+					x1 := forward[j]
+					y1 := offset + x1 - j
+					if x1 >= n-x2 {
+						return x1, y1, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// sortChanges moves deletions before insertions
+// in every group of consecutive changes.
+func sortChanges(ops []DiffOp) []DiffOp {
+	for start := 0; start < len(ops); {
+		if ops[start] == DiffEqual {
+			start++
+			continue
+		}
+
+		end, deletes := start, 0
+		for ; end < len(ops) && ops[end] != DiffEqual; end++ {
+			if ops[end] == DiffDelete {
+				deletes++
+			}
+		}
+		for i := start; i < end; i++ {
+			if i < start+deletes {
+				ops[i] = DiffDelete
+			} else {
+				ops[i] = DiffInsert
+			}
+		}
+		start = end
+	}
+	return ops
+}
+
+// HasChanges reports whether there are deleted or inserted lines.
+func (s EditScript) HasChanges() bool {
+	for _, edit := range s {
+		if edit.Op != DiffEqual && !edit.Ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// hunks returns ranges of edits, which contain changes
+// with up to context equal lines around them.
+// Changes closer than 2*context lines are joined to one range.
+func (s EditScript) hunks(context int) [][2]int {
+	var ranges [][2]int
+	for i := 0; i < len(s); i++ {
+		if s[i].Op == DiffEqual || s[i].Ignored {
+			continue
+		}
+
+		start := max(0, i-context)
+		end := i
+		for unchanged := 0; end < len(s) && unchanged <= 2*context; end++ {
+			if s[end].Op == DiffEqual || s[end].Ignored {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		for end > i && (s[end-1].Op == DiffEqual || s[end-1].Ignored) {
+			end--
+		}
+		end = min(len(s), end+context)
+
+		ranges = append(ranges, [2]int{start, end})
+		i = end
+	}
+	return ranges
+}
+
+// Unified renders script as unified diff with context lines
+// around changes, like diff -u does.
+// Returns empty string, if there aren't changes.
+func (s EditScript) Unified(oldName, newName string, context int) string {
+	hunks := s.hunks(context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "--- %v\n+++ %v\n", oldName, newName)
+
+	for _, hunk := range hunks {
+		edits := s[hunk[0]:hunk[1]]
+		oldCount, newCount := 0, 0
+		for _, edit := range edits {
+			if edit.Op != DiffInsert {
+				oldCount++
+			}
+			if edit.Op != DiffDelete {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(&builder, "@@ -%v +%v @@\n",
+			hunkRange(edits[0].OldLine, oldCount), hunkRange(edits[0].NewLine, newCount))
+		for _, edit := range edits {
+			switch edit.Op {
+			case DiffEqual:
+				fmt.Fprintf(&builder, " %v\n", edit.OldText)
+			case DiffDelete:
+				fmt.Fprintf(&builder, "-%v\n", edit.OldText)
+			case DiffInsert:
+				fmt.Fprintf(&builder, "+%v\n", edit.NewText)
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+// hunkRange formats range of hunk header.
+// Empty range points to line before it.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%v,0", start)
+	case 1:
+		return fmt.Sprintf("%v", start+1)
+	}
+	return fmt.Sprintf("%v,%v", start+1, count)
+}
+
+// SideBySide renders script in two columns, like diff -y does.
+// Changed lines are marked by "|", deleted by "<" and inserted by ">".
+// Lines longer than column are truncated.
+func (s EditScript) SideBySide(width int) string {
+	column := max(1, (width-3)/2)
+
+	var builder strings.Builder
+	row := func(left, marker, right string) {
+		line := fmt.Sprintf("%-*s %v %v", column, truncateText(left, column), marker, truncateText(right, column))
+		builder.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+
+	for i := 0; i < len(s); {
+		if s[i].Op == DiffEqual {
+			row(s[i].OldText, " ", s[i].NewText)
+			i++
+			continue
+		}
+
+		var deleted, inserted []string
+		for ; i < len(s) && s[i].Op != DiffEqual; i++ {
+			if s[i].Op == DiffDelete {
+				deleted = append(deleted, s[i].OldText)
+			} else {
+				inserted = append(inserted, s[i].NewText)
+			}
+		}
+
+		for j := 0; j < max(len(deleted), len(inserted)); j++ {
+			switch {
+			case j >= len(deleted):
+				row("", ">", inserted[j])
+			case j >= len(inserted):
+				row(deleted[j], "<", "")
+			default:
+				row(deleted[j], "|", inserted[j])
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+// truncateText cuts text to width runes.
+func truncateText(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	return string([]rune(text)[:width])
+}
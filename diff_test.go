@@ -0,0 +1,137 @@
+package fs_utils
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// applyScript builds new lines from old ones by script.
+func applyScript(old FileLines, script EditScript) FileLines {
+	var result FileLines
+	i := 0
+	for _, edit := range script {
+		switch edit.Op {
+		case DiffEqual:
+			result = append(result, old[i])
+			i++
+		case DiffDelete:
+			i++
+		case DiffInsert:
+			result = append(result, edit.NewText)
+		}
+	}
+	return result
+}
+
+// lcsLength returns length of longest common subsequence.
+func lcsLength(a, b FileLines) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				table[i][j] = table[i+1][j+1] + 1
+			} else {
+				table[i][j] = max(table[i+1][j], table[i][j+1])
+			}
+		}
+	}
+	return table[0][0]
+}
+
+func TestDiffShortest(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomLines := func() FileLines {
+		lines := make(FileLines, random.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + random.Intn(4)))
+		}
+		return lines
+	}
+
+	for n := 0; n < 500; n++ {
+		old, new := randomLines(), randomLines()
+		script := Diff(old, new, DiffOptions{})
+
+		if result := applyScript(old, script); strings.Join(result, "\n") != strings.Join(new, "\n") {
+			t.Fatalf("expected script to turn %v into %v, got: %v", old, new, result)
+		}
+
+		changes := 0
+		for _, edit := range script {
+			if edit.Op != DiffEqual {
+				changes++
+			}
+		}
+		if want := len(old) + len(new) - 2*lcsLength(old, new); changes != want {
+			t.Fatalf("expected %v changes between %v and %v, got: %v", want, old, new, changes)
+		}
+	}
+}
+
+func TestDiffUnified(t *testing.T) {
+	old := FileLines{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	new := FileLines{"a", "B", "c", "d", "e", "f", "g", "h", "i", "j", "k"}
+
+	expected := "--- old\n+++ new\n" +
+		"@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n" +
+		"@@ -10 +10,2 @@\n j\n+k\n"
+	if diff := Diff(old, new, DiffOptions{}).Unified("old", "new", 1); diff != expected {
+		t.Errorf("expected diff:\n%v\ngot:\n%v", expected, diff)
+	}
+
+	if diff := Diff(old, old, DiffOptions{}).Unified("old", "new", 3); diff != "" {
+		t.Errorf("expected empty diff for equal lines, got:\n%v", diff)
+	}
+}
+
+func TestDiffOptions(t *testing.T) {
+	old := FileLines{"func main() {", "", "\treturn", "}"}
+	new := FileLines{"func  main() {", "    return", "", "", "}"}
+
+	if Diff(old, new, DiffOptions{}).HasChanges() == false {
+		t.Errorf("expected changes without options")
+	}
+	if script := Diff(old, new, DiffOptions{IgnoreWhitespace: true, IgnoreBlankLines: true}); script.HasChanges() {
+		t.Errorf("expected no changes when whitespace is ignored, got: %v", script)
+	}
+}
+
+// Test line numbers of hunks when blank lines are ignored
+func TestDiffIgnoreBlankLinesUnified(t *testing.T) {
+	old := FileLines{"a", "", "", "b", "c", "d", "e", "f", "", "g", "h"}
+	new := FileLines{"a", "", "b", "c", "d", "e", "f", "", "g", "x"}
+
+	diff := Diff(old, new, DiffOptions{IgnoreBlankLines: true}).Unified("old", "new", 1)
+	if !strings.Contains(diff, "@@ -10,2 +9,2 @@\n g\n-h\n+x\n") {
+		t.Errorf("expected original line numbers in hunk, got:\n%v", diff)
+	}
+
+	patches, err := ParsePatch(Diff(old, new, DiffOptions{IgnoreBlankLines: true}).Unified("old", "new", 3))
+	if err != nil {
+		t.Fatalf("expected to parse diff, error: %v", err)
+	}
+	// Change of blank lines isn't in diff
+	expected := FileLines{"a", "", "", "b", "c", "d", "e", "f", "", "g", "x"}
+	result, rejected := ApplyHunks(old, patches[0].Hunks, 0)
+	if len(rejected) != 0 || strings.Join(result, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected diff to apply, got: %q, rejected: %v", result, rejected)
+	}
+}
+
+func TestDiffSideBySide(t *testing.T) {
+	script := Diff(FileLines{"a", "b", "c"}, FileLines{"a", "x", "c", "d"}, DiffOptions{})
+
+	expected := []string{
+		"a          a",
+		"b        | x",
+		"c          c",
+		"         > d",
+	}
+	if view := script.SideBySide(19); view != strings.Join(expected, "\n")+"\n" {
+		t.Errorf("expected side by side view:\n%v\ngot:\n%v", strings.Join(expected, "\n"), view)
+	}
+}
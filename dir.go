@@ -132,26 +132,9 @@ func ReadDirQ(path string) (*Dir, error) {
 }
 
 // ReadDirW reads directory and outputs content with fmt.Printf.
+// Use FprintDir to write it to other io.Writer.
 func ReadDirW(path string) error {
-	err := filepath.Walk(path, func(location string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if info.IsDir() {
-			fmt.Printf("found dir: %v\n", location)
-		} else {
-			fmt.Printf("found file: %v\n", location)
-		}
-
-		return nil
-	})
-
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return FprintDir(os.Stdout, path)
 }
 
 // ReadDirA works same as ReadDir etc.
@@ -188,24 +171,9 @@ func ReadDirA(d *Dir) error {
 // Generates random ID to identify an operation.
 // Returns ID.
 // If there's an error, then functions outputs error instead of panic.
+// Use FprintDirD to write it to other io.Writer.
 func ReadDirD(path string) string {
-	id := generateID(16)
-	fmt.Printf("%v: starting scanning directory... (path: %v)\n", id, path)
-
-	err := filepath.Walk(path, func(location string, info fs.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if info.IsDir() {
-			fmt.Printf("found directory: %v\n", location)
-		} else {
-			fmt.Printf("found file: %v\n", location)
-		}
-
-		return nil
-	})
-
+	id, err := FprintDirD(os.Stdout, path)
 	if err != nil {
 		fmt.Printf("error while scanning: %v", err)
 	}
@@ -214,8 +182,9 @@ func ReadDirD(path string) string {
 }
 
 // CreateDir creates directory to specific path with os.Mkdir.
+// Mode is taken from DefaultPerm, see CreateDirPerm.
 func CreateDir(path string) error {
-	err := os.Mkdir(path, os.ModePerm)
+	_, err := mkdirPerm(path, DefaultPerm)
 	if err != nil {
 		return err
 	}
@@ -224,7 +193,7 @@ func CreateDir(path string) error {
 
 // CreateDirQ creates directory to specific path with os.MkdirAll.
 func CreateDirQ(path string) error {
-	err := os.MkdirAll(path, os.ModePerm)
+	_, err := mkdirAllPerm(path, DefaultPerm)
 	if err != nil {
 		return err
 	}
@@ -234,15 +203,25 @@ func CreateDirQ(path string) error {
 // CreateDirW creates directory to specific path with os.Mkdir.
 // Then returns Dir object.
 func CreateDirW(path string) (*Dir, error) {
-	err := os.Mkdir(path, os.ModePerm)
-	if err != nil {
-		return nil, err
-	}
-	return &Dir{Path: path}, nil
+	d, _, err := CreateDirWPerm(path, DefaultPerm)
+	return d, err
 }
 
 // RemoveDirQ removes a directory from specific path.
+// Refuses to remove paths which DefaultRemoveGuard doesn't allow,
+// like filesystem root, home directory or mount points.
 func RemoveDirQ(path string) error {
+	return RemoveDirGuarded(path, DefaultRemoveGuard)
+}
+
+// RemoveDirGuarded works same as RemoveDirQ,
+// but runs checks of specific guard before removal.
+// If guard refuses removal, returns *GuardError.
+func RemoveDirGuarded(path string, guard RemoveGuard) error {
+	if err := CheckRemove(path, guard); err != nil {
+		return err
+	}
+
 	if !strings.HasSuffix(path, "/") {
 		path = path + "/"
 	}
@@ -290,8 +269,9 @@ func RemoveDirA(d *Dir) ([]string, error) {
 }
 
 // Output outputs directory with fmt.Printf.
+// Use Fprint to write it to other io.Writer.
 func (d Dir) Output() {
-	fmt.Printf("Path: %v\nChildren: %v\n", d.Path, d.Children)
+	_ = d.Fprint(os.Stdout)
 }
 
 // MoveDir moves a directory from sourcePath to destinationPath.
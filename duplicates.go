@@ -0,0 +1,242 @@
+package fs_utils
+
+import (
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"sort"
+)
+
+// partialHashSize is how many leading bytes are hashed
+// before files are hashed fully.
+const partialHashSize = 4096
+
+// Stages of FindDuplicates reported by progress.
+const (
+	DuplicateStageScan    = "scan"
+	DuplicateStagePartial = "partial"
+	DuplicateStageFull    = "full"
+)
+
+// DuplicateOptions configures FindDuplicates.
+type DuplicateOptions struct {
+	// MinSize is minimal size of compared files.
+	// Empty files are always skipped.
+	MinSize int64
+	// Include and Exclude filter files like in SearchOptions.
+	Include []string
+	Exclude []string
+	// Algorithm is SHA256, if it isn't set.
+	Algorithm HashAlgorithm
+	// Progress is called after every processed file.
+	Progress func(DuplicateProgress)
+}
+
+// DuplicateProgress describes progress of FindDuplicates.
+// Total is number of files, which are processed at Stage.
+type DuplicateProgress struct {
+	Stage string
+	Done  int
+	Total int
+}
+
+// DuplicateGroup contains paths of files with same content.
+// Hash is hex-encoded checksum of content.
+type DuplicateGroup struct {
+	Size  int64
+	Hash  string
+	Paths []string
+}
+
+// dupFile is a file considered by FindDuplicates.
+type dupFile struct {
+	path string
+	info os.FileInfo
+}
+
+// dupGroup is a group of files with same hash.
+type dupGroup struct {
+	files []dupFile
+	hash  string
+}
+
+// FindDuplicates searches files with same content under roots.
+// Files are grouped by size, then by hash of leading 4 KiB,
+// and only then by hash of whole content, so most files are read partially.
+// Hardlinks of one file are considered as one file.
+// Groups are sorted by size in descending order.
+// Files which couldn't be read are reported by error,
+// groups of other files are still returned.
+// Files removed while searching are skipped.
+func FindDuplicates(roots []string, opts DuplicateOptions) ([]DuplicateGroup, error) {
+	if opts.Algorithm == "" {
+		opts.Algorithm = SHA256
+	}
+	progress := func(stage string, done, total int) {
+		if opts.Progress != nil {
+			opts.Progress(DuplicateProgress{stage, done, total})
+		}
+	}
+
+	bySize := make(map[int64][]dupFile)
+	var paths []string
+	var errs []error
+	for _, root := range roots {
+		files, err := filterFiles(root, opts.Include, opts.Exclude)
+		errs = append(errs, err)
+		paths = append(paths, files...)
+	}
+
+	for i, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+		} else if info.Size() > 0 && info.Size() >= opts.MinSize {
+			bySize[info.Size()] = append(bySize[info.Size()], dupFile{path, info})
+		}
+		progress(DuplicateStageScan, i+1, len(paths))
+	}
+
+	var candidates []dupGroup
+	for _, files := range bySize {
+		if files = uniqueFiles(files); len(files) > 1 {
+			candidates = append(candidates, dupGroup{files: files})
+		}
+	}
+
+	candidates, err := splitByHash(candidates, opts.Algorithm, partialHashSize, DuplicateStagePartial, progress)
+	errs = append(errs, err)
+
+	// Leading bytes of small files are their whole content,
+	// so only large files are hashed again.
+	var large []dupGroup
+	var groups []DuplicateGroup
+	for _, group := range candidates {
+		if group.files[0].info.Size() > partialHashSize {
+			large = append(large, group)
+			continue
+		}
+		groups = append(groups, newDuplicateGroup(group))
+	}
+
+	large, err = splitByHash(large, opts.Algorithm, 0, DuplicateStageFull, progress)
+	errs = append(errs, err)
+	for _, group := range large {
+		groups = append(groups, newDuplicateGroup(group))
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Size != groups[j].Size {
+			return groups[i].Size > groups[j].Size
+		}
+		return groups[i].Paths[0] < groups[j].Paths[0]
+	})
+	return groups, errors.Join(errs...)
+}
+
+// uniqueFiles removes hardlinks of already listed files.
+func uniqueFiles(files []dupFile) []dupFile {
+	var unique []dupFile
+	ids := make(map[[2]uint64]bool)
+	for _, file := range files {
+		if id, ok := fileID(file.info); ok {
+			if ids[id] {
+				continue
+			}
+			ids[id] = true
+		} else if containsSameFile(unique, file.info) {
+			continue
+		}
+		unique = append(unique, file)
+	}
+	return unique
+}
+
+// containsSameFile reports whether files contain file described by info.
+func containsSameFile(files []dupFile, info os.FileInfo) bool {
+	for _, file := range files {
+		if os.SameFile(file.info, info) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitByHash splits every group of files by hash of first limit bytes,
+// or whole content if limit is 0.
+// Groups with single file are dropped.
+// Files which couldn't be hashed are left out and reported by error,
+// removed files are left out silently.
+func splitByHash(groups []dupGroup, algo HashAlgorithm, limit int64, stage string,
+	progress func(stage string, done, total int)) ([]dupGroup, error) {
+	total := 0
+	for _, group := range groups {
+		total += len(group.files)
+	}
+
+	var result []dupGroup
+	var errs []error
+	done := 0
+	for _, group := range groups {
+		byHash := make(map[string][]dupFile)
+		var order []string
+		for _, file := range group.files {
+			sum, err := hashPrefix(file.path, algo, limit)
+			done++
+			progress(stage, done, total)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					errs = append(errs, err)
+				}
+				continue
+			}
+
+			if _, ok := byHash[sum]; !ok {
+				order = append(order, sum)
+			}
+			byHash[sum] = append(byHash[sum], file)
+		}
+
+		for _, sum := range order {
+			if len(byHash[sum]) > 1 {
+				result = append(result, dupGroup{byHash[sum], sum})
+			}
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// hashPrefix returns hex-encoded checksum of first limit bytes of file,
+// or whole content if limit is 0.
+func hashPrefix(path string, algo HashAlgorithm, limit int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if limit > 0 {
+		r = io.LimitReader(file, limit)
+	}
+
+	sum, err := hashReader(r, algo)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// newDuplicateGroup returns group of files with sorted paths.
+func newDuplicateGroup(group dupGroup) DuplicateGroup {
+	result := DuplicateGroup{Size: group.files[0].info.Size(), Hash: group.hash}
+	for _, file := range group.files {
+		result.Paths = append(result.Paths, file.path)
+	}
+	sort.Strings(result.Paths)
+	return result
+}
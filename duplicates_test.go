@@ -0,0 +1,118 @@
+package fs_utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+
+	large := bytes.Repeat([]byte("a"), 2*partialHashSize)
+	changed := bytes.Clone(large)
+	changed[len(changed)-1] = 'b'
+
+	os.WriteFile(filepath.Join(first, "a.txt"), []byte("same"), 0644)
+	os.WriteFile(filepath.Join(second, "b.txt"), []byte("same"), 0644)
+	os.WriteFile(filepath.Join(first, "c.txt"), []byte("diff"), 0644)
+	os.WriteFile(filepath.Join(first, "empty1.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(first, "empty2.txt"), nil, 0644)
+	os.WriteFile(filepath.Join(first, "large1.bin"), large, 0644)
+	os.WriteFile(filepath.Join(second, "large2.bin"), large, 0644)
+	os.WriteFile(filepath.Join(second, "large3.bin"), changed, 0644)
+
+	var stages []string
+	groups, err := FindDuplicates([]string{first, second}, DuplicateOptions{
+		Progress: func(p DuplicateProgress) {
+			if p.Done > p.Total {
+				t.Errorf("expected done %v to be not greater than total %v", p.Done, p.Total)
+			}
+			if len(stages) == 0 || stages[len(stages)-1] != p.Stage {
+				stages = append(stages, p.Stage)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected to find duplicates, error: %v", err)
+	}
+
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got: %v", groups)
+	}
+	expected := []string{filepath.Join(first, "large1.bin"), filepath.Join(second, "large2.bin")}
+	if groups[0].Size != int64(len(large)) || !reflect.DeepEqual(groups[0].Paths, expected) {
+		t.Errorf("expected large group %v, got: %v", expected, groups[0])
+	}
+	expected = []string{filepath.Join(first, "a.txt"), filepath.Join(second, "b.txt")}
+	if !reflect.DeepEqual(groups[1].Paths, expected) {
+		t.Errorf("expected small group %v, got: %v", expected, groups[1])
+	}
+
+	sum, _ := HashFile(filepath.Join(first, "a.txt"), SHA256)
+	if groups[1].Hash != sum {
+		t.Errorf("expected hash %v, got: %v", sum, groups[1].Hash)
+	}
+
+	expectedStages := []string{DuplicateStageScan, DuplicateStagePartial, DuplicateStageFull}
+	if !reflect.DeepEqual(stages, expectedStages) {
+		t.Errorf("expected stages %v, got: %v", expectedStages, stages)
+	}
+}
+
+func TestFindDuplicatesOptions(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("same"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "b.txt"), []byte("same"), 0644)
+	if err := os.Link(filepath.Join(tempDir, "a.txt"), filepath.Join(tempDir, "c.txt")); err != nil {
+		t.Skipf("hardlinks aren't supported: %v", err)
+	}
+
+	groups, err := FindDuplicates([]string{tempDir}, DuplicateOptions{})
+	if err != nil {
+		t.Fatalf("expected to find duplicates, error: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Paths) != 2 {
+		t.Errorf("expected hardlink to be skipped, got: %v", groups)
+	}
+
+	groups, err = FindDuplicates([]string{tempDir}, DuplicateOptions{MinSize: 5})
+	if err != nil {
+		t.Fatalf("expected to find duplicates, error: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("expected small files to be skipped, got: %v", groups)
+	}
+}
+
+// Test returning partial groups
+func TestFindDuplicatesPartial(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		os.WriteFile(filepath.Join(tempDir, name), []byte("same"), 0644)
+	}
+	missing := filepath.Join(tempDir, "missing")
+
+	// File is removed after scanning, before it's hashed
+	groups, err := FindDuplicates([]string{tempDir, missing}, DuplicateOptions{
+		Progress: func(p DuplicateProgress) {
+			if p.Stage == DuplicateStageScan && p.Done == p.Total {
+				os.Remove(filepath.Join(tempDir, "c.txt"))
+			}
+		},
+	})
+	if err == nil {
+		t.Errorf("expected error for missing root: %v", missing)
+	}
+	expected := []string{filepath.Join(tempDir, "a.txt"), filepath.Join(tempDir, "b.txt")}
+	if len(groups) != 1 || !reflect.DeepEqual(groups[0].Paths, expected) {
+		t.Errorf("expected group %v, got: %v", expected, groups)
+	}
+	if err != nil && strings.Contains(err.Error(), "c.txt") {
+		t.Errorf("expected removed file to be skipped, got: %v", err)
+	}
+}
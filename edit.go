@@ -0,0 +1,369 @@
+package fs_utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// ErrModified is returned by File.Save when the file was changed
+// by someone else since it was loaded.
+var ErrModified = errors.New("file was modified externally")
+
+// fileStamp identifies version of file on disk.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// statStamp returns current stamp of file at path.
+func statStamp(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{info.ModTime(), info.Size()}, nil
+}
+
+// fileStamps keeps stamps of Files returned by this package,
+// so File itself has only exported fields.
+// Files are keyed by address, which doesn't keep them alive,
+// and entry is removed when its File is collected.
+var fileStamps = struct {
+	sync.Mutex
+	stamps map[uintptr]fileStamp
+}{stamps: make(map[uintptr]fileStamp)}
+
+// trackFile starts keeping stamp of f, which is located in owner.
+// Owner must be allocated by caller, so it can have finalizer.
+func trackFile[T any](owner *T, f *File, stamp fileStamp) {
+	key := uintptr(unsafe.Pointer(f))
+	fileStamps.Lock()
+	fileStamps.stamps[key] = stamp
+	fileStamps.Unlock()
+
+	runtime.SetFinalizer(owner, func(*T) {
+		fileStamps.Lock()
+		delete(fileStamps.stamps, key)
+		fileStamps.Unlock()
+	})
+}
+
+// getStamp returns stamp of f.
+// Returns false, if f isn't tracked.
+func (f *File) getStamp() (fileStamp, bool) {
+	fileStamps.Lock()
+	defer fileStamps.Unlock()
+	stamp, ok := fileStamps.stamps[uintptr(unsafe.Pointer(f))]
+	return stamp, ok
+}
+
+// setStamp replaces stamp of f, if f is tracked.
+func (f *File) setStamp(stamp fileStamp) {
+	fileStamps.Lock()
+	defer fileStamps.Unlock()
+	key := uintptr(unsafe.Pointer(f))
+	if _, ok := fileStamps.stamps[key]; ok {
+		fileStamps.stamps[key] = stamp
+	}
+}
+
+// newFile returns File with stamp of file on disk,
+// so later external modifications can be detected.
+func newFile(path string, content FileLines) *File {
+	stamp, _ := statStamp(path)
+	f := &File{Path: path, Content: content}
+	trackFile(f, f, stamp)
+	return f
+}
+
+// ReadFileQ reads file at a specific path and returns File.
+// Unlike GetFileContent, lines aren't limited in length.
+func ReadFileQ(path string) (*File, error) {
+	stamp, err := statStamp(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := readFileLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{Path: path, Content: content}
+	trackFile(f, f, stamp)
+	return f, nil
+}
+
+// readFileLines reads whole file and splits it to lines.
+func readFileLines(path string) (FileLines, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(string(data)), nil
+}
+
+// Save writes Content back to the file atomically:
+// content is written to temporary file, which then replaces the file.
+// Mode of the file is kept.
+// If the file doesn't exist, it's created with DefaultPerm.
+// If the file was modified or removed externally since it was loaded,
+// returns ErrModified. Use Reload to get external changes.
+// Files constructed without loading, like File{Path: path},
+// aren't checked.
+func (f *File) Save() error {
+	stamp, _ := f.getStamp()
+	loaded := !stamp.modTime.IsZero()
+
+	info, err := os.Stat(f.Path)
+	switch {
+	case os.IsNotExist(err) && loaded:
+		return fmt.Errorf("%v: %w", f.Path, ErrModified)
+	case os.IsNotExist(err):
+		err = createExclusiveW(f.Path, f.Content)
+	case err != nil:
+		return err
+	default:
+		if loaded && stamp != (fileStamp{info.ModTime(), info.Size()}) {
+			return fmt.Errorf("%v: %w", f.Path, ErrModified)
+		}
+		err = writeAtomic(f.Path, info.Mode().Perm(), func(w io.Writer) error {
+			return writeLines(w, f.Content)
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	stamp, err = statStamp(f.Path)
+	f.setStamp(stamp)
+	return err
+}
+
+// Reload reads Content from the file again,
+// if the file was modified since it was loaded or saved.
+// Returns true, if the file was modified.
+// Local changes of Content are lost then.
+func (f *File) Reload() (bool, error) {
+	stamp, err := statStamp(f.Path)
+	if err != nil {
+		return false, err
+	}
+	if current, _ := f.getStamp(); stamp == current {
+		return false, nil
+	}
+
+	content, err := readFileLines(f.Path)
+	if err != nil {
+		return false, err
+	}
+
+	f.Content = content
+	f.setStamp(stamp)
+	return true, nil
+}
+
+// writeAtomic writes file through temporary file in the same directory,
+// which replaces the file by rename.
+// So readers never see partially written file.
+// Symlinks are followed, so the file they point to is replaced.
+// Owner and group of existing file are kept. If they can't be kept,
+// or file has other hardlinks, which rename would detach from it,
+// file is written in place instead.
+func writeAtomic(path string, mode os.FileMode, write func(w io.Writer) error) error {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	info, err := os.Lstat(path)
+	exists := err == nil
+	if exists {
+		if links, ok := fileLinks(info); ok && links > 1 {
+			return writeInPlace(path, write)
+		}
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tempPath := temp.Name()
+
+	err = write(temp)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && exists && !keepOwner(tempPath, info) {
+		_ = os.Remove(tempPath)
+		return writeInPlace(path, write)
+	}
+	if err == nil {
+		err = os.Chmod(tempPath, mode)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	return nil
+}
+
+// writeFile creates file with mode from perm, if it doesn't exist.
+// Otherwise file is replaced by writeAtomic and keeps its mode.
+func writeFile(path string, perm Perm, write func(w io.Writer) error) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		file, _, err := createExclusive(path, perm)
+		if err != nil {
+			return err
+		}
+		err = write(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(path, info.Mode().Perm(), write)
+}
+
+// keepOwner gives file at path owner and group from info.
+// Returns false, if they couldn't be given.
+func keepOwner(path string, info os.FileInfo) bool {
+	uid, gid, ok := fileOwner(info)
+	if !ok {
+		return true
+	}
+
+	current, err := os.Lstat(path)
+	if err != nil {
+		return false
+	}
+	if currentUID, currentGID, _ := fileOwner(current); currentUID == uid && currentGID == gid {
+		return true
+	}
+	return os.Chown(path, uid, gid) == nil
+}
+
+// writeInPlace truncates file at path and writes it again.
+func writeInPlace(path string, write func(w io.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+
+	err = write(file)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// checkRange checks that lines from start to end (exclusive) exist.
+func (fl FileLines) checkRange(start, end int) error {
+	if start < 0 || end < start || end > len(fl) {
+		return fmt.Errorf("invalid range [%v, %v) of %v lines", start, end, len(fl))
+	}
+	return nil
+}
+
+// InsertAt inserts lines before line i.
+// If i equals to number of lines, then lines are appended.
+func (fl *FileLines) InsertAt(i int, lines ...string) error {
+	return fl.ReplaceRange(i, i, lines...)
+}
+
+// DeleteRange deletes lines from start to end (exclusive).
+func (fl *FileLines) DeleteRange(start, end int) error {
+	return fl.ReplaceRange(start, end)
+}
+
+// ReplaceRange replaces lines from start to end (exclusive) with lines.
+// Lines are indexed from 0.
+func (fl *FileLines) ReplaceRange(start, end int, lines ...string) error {
+	if err := fl.checkRange(start, end); err != nil {
+		return err
+	}
+
+	result := make(FileLines, 0, len(*fl)-(end-start)+len(lines))
+	result = append(result, (*fl)[:start]...)
+	result = append(result, lines...)
+	result = append(result, (*fl)[end:]...)
+	*fl = result
+	return nil
+}
+
+// Find returns indexes of lines which contain substr.
+func (fl FileLines) Find(substr string) []int {
+	var found []int
+	for i, line := range fl {
+		if strings.Contains(line, substr) {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+// FindRegexp returns indexes of lines which match re.
+func (fl FileLines) FindRegexp(re *regexp.Regexp) []int {
+	var found []int
+	for i, line := range fl {
+		if re.MatchString(line) {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+// ReplaceAll replaces all occurrences of old with new in every line.
+// Returns number of replacements.
+func (fl FileLines) ReplaceAll(old, new string) int {
+	if old == "" {
+		return 0
+	}
+
+	count := 0
+	for i, line := range fl {
+		if n := strings.Count(line, old); n > 0 {
+			fl[i] = strings.ReplaceAll(line, old, new)
+			count += n
+		}
+	}
+	return count
+}
+
+// ReplaceAllRegexp replaces all matches of re in every line with repl.
+// Inside repl, $1 and ${name} are expanded like in regexp.Regexp.Expand.
+// Returns number of replacements.
+func (fl FileLines) ReplaceAllRegexp(re *regexp.Regexp, repl string) int {
+	count := 0
+	for i, line := range fl {
+		if n := len(re.FindAllStringIndex(line, -1)); n > 0 {
+			fl[i] = re.ReplaceAllString(line, repl)
+			count += n
+		}
+	}
+	return count
+}
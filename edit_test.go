@@ -0,0 +1,213 @@
+package fs_utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileLinesEditing(t *testing.T) {
+	lines := FileLines{"a", "b", "c"}
+
+	if err := lines.InsertAt(1, "x", "y"); err != nil {
+		t.Fatalf("expected to insert lines, error: %v", err)
+	}
+	if !reflect.DeepEqual(lines, FileLines{"a", "x", "y", "b", "c"}) {
+		t.Errorf("expected lines to be inserted, got: %v", lines)
+	}
+
+	if err := lines.DeleteRange(0, 2); err != nil {
+		t.Fatalf("expected to delete lines, error: %v", err)
+	}
+	if !reflect.DeepEqual(lines, FileLines{"y", "b", "c"}) {
+		t.Errorf("expected lines to be deleted, got: %v", lines)
+	}
+
+	if err := lines.ReplaceRange(1, 3, "z"); err != nil {
+		t.Fatalf("expected to replace lines, error: %v", err)
+	}
+	if !reflect.DeepEqual(lines, FileLines{"y", "z"}) {
+		t.Errorf("expected lines to be replaced, got: %v", lines)
+	}
+
+	// Test invalid ranges
+	if err := lines.DeleteRange(1, 3); err == nil {
+		t.Errorf("expected error for range out of lines")
+	}
+	if err := lines.InsertAt(-1, "x"); err == nil {
+		t.Errorf("expected error for negative index")
+	}
+}
+
+func TestFileLinesFindReplace(t *testing.T) {
+	lines := FileLines{"foo bar", "baz", "foo foo"}
+
+	if found := lines.Find("foo"); !reflect.DeepEqual(found, []int{0, 2}) {
+		t.Errorf("expected lines 0 and 2 to be found, got: %v", found)
+	}
+	if found := lines.FindRegexp(regexp.MustCompile(`^ba`)); !reflect.DeepEqual(found, []int{1}) {
+		t.Errorf("expected line 1 to be found, got: %v", found)
+	}
+
+	if n := lines.ReplaceAll("foo", "qux"); n != 3 {
+		t.Errorf("expected 3 replacements, got: %v", n)
+	}
+	if n := lines.ReplaceAllRegexp(regexp.MustCompile(`ba(\w)`), "B$1"); n != 2 {
+		t.Errorf("expected 2 replacements, got: %v", n)
+	}
+	if !reflect.DeepEqual(lines, FileLines{"qux Br", "Bz", "qux qux"}) {
+		t.Errorf("expected lines to be replaced, got: %v", lines)
+	}
+}
+
+func TestFileSaveReload(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.txt")
+	os.WriteFile(path, []byte("a\nb\n"), 0640)
+
+	f, err := ReadFileQ(path)
+	if err != nil {
+		t.Fatalf("expected to read file: %v, error: %v", path, err)
+	}
+
+	// Test saving
+	f.Content.InsertAt(1, "x")
+	if err := f.Save(); err != nil {
+		t.Fatalf("expected to save file, error: %v", err)
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != "a\nx\nb\n" {
+		t.Errorf("expected content to be saved, got: %q", content)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("expected mode to be kept, got: %v", info.Mode())
+	}
+
+	// Test reload without changes
+	if changed, err := f.Reload(); err != nil || changed {
+		t.Errorf("expected file to be unchanged, error: %v", err)
+	}
+
+	// Test external modification
+	os.WriteFile(path, []byte("external\n"), 0640)
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(path, future, future)
+
+	if err := f.Save(); !errors.Is(err, ErrModified) {
+		t.Errorf("expected ErrModified, got: %v", err)
+	}
+	if changed, err := f.Reload(); err != nil || !changed {
+		t.Errorf("expected file to be changed, error: %v", err)
+	}
+	if !reflect.DeepEqual(f.Content, FileLines{"external"}) {
+		t.Errorf("expected content to be reloaded, got: %v", f.Content)
+	}
+	// Test external removal
+	os.Remove(path)
+	if err := f.Save(); !errors.Is(err, ErrModified) || IsFileExists(path) {
+		t.Errorf("expected ErrModified without recreating file, got: %v", err)
+	}
+}
+
+func TestFileSaveLinks(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.txt")
+	symlink := filepath.Join(tempDir, "symlink.txt")
+	hardlink := filepath.Join(tempDir, "hardlink.txt")
+	os.WriteFile(path, []byte("a\n"), 0644)
+	if err := os.Symlink(path, symlink); err != nil {
+		t.Skipf("symlinks aren't supported: %v", err)
+	}
+
+	// Test saving through symlink
+	f, _ := ReadFileQ(symlink)
+	f.Content = FileLines{"b"}
+	if err := f.Save(); err != nil {
+		t.Fatalf("expected to save file, error: %v", err)
+	}
+	if info, _ := os.Lstat(symlink); info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected symlink to be kept")
+	}
+	if content, _ := os.ReadFile(path); string(content) != "b\n" {
+		t.Errorf("expected target to be saved, got: %q", content)
+	}
+
+	// Test saving file with hardlink
+	if err := os.Link(path, hardlink); err != nil {
+		t.Skipf("hardlinks aren't supported: %v", err)
+	}
+	f, _ = ReadFileQ(path)
+	f.Content = FileLines{"c"}
+	if err := f.Save(); err != nil {
+		t.Fatalf("expected to save file, error: %v", err)
+	}
+	if content, _ := os.ReadFile(hardlink); string(content) != "c\n" {
+		t.Errorf("expected hardlink to be kept, got: %q", content)
+	}
+	// Test keeping owner, which only root can change
+	os.Remove(hardlink)
+	if os.Getuid() == 0 {
+		os.Chown(path, 1000, 1000)
+		f, _ = ReadFileQ(path)
+		if err := f.Save(); err != nil {
+			t.Fatalf("expected to save file, error: %v", err)
+		}
+		info, _ := os.Stat(path)
+		if uid, gid, ok := fileOwner(info); ok && (uid != 1000 || gid != 1000) {
+			t.Errorf("expected owner to be kept, got: %v:%v", uid, gid)
+		}
+	}
+}
+
+func TestFileSaveLongLine(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "long.txt")
+	long := strings.Repeat("x", 70*1024)
+	os.WriteFile(path, []byte(long+"\nend\n"), 0644)
+
+	// Test reading line longer than bufio.Scanner limit
+	f, err := ReadFileQ(path)
+	if err != nil {
+		t.Fatalf("expected to read file: %v, error: %v", path, err)
+	}
+	if len(f.Content) != 2 || f.Content[0] != long {
+		t.Fatalf("expected long line to be read whole, got %v lines", len(f.Content))
+	}
+
+	if err := f.Save(); err != nil {
+		t.Fatalf("expected to save file, error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != long+"\nend\n" {
+		t.Errorf("expected content to be kept, got %v bytes", len(data))
+	}
+
+	// Test GetFileContent reporting the limit instead of truncating
+	if _, err := GetFileContent(path); err == nil {
+		t.Errorf("expected error for too long line")
+	}
+}
+
+func TestFileSaveNewFileMode(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "new.txt")
+	reference := filepath.Join(tempDir, "reference.txt")
+
+	f := &File{Path: path, Content: FileLines{"new"}}
+	if err := f.Save(); err != nil {
+		t.Fatalf("expected to save new file, error: %v", err)
+	}
+
+	// Mode is filtered by umask, same as os.Create does
+	file, _ := os.Create(reference)
+	file.Close()
+	info, _ := os.Stat(path)
+	referenceInfo, _ := os.Stat(reference)
+	if info.Mode() != referenceInfo.Mode() {
+		t.Errorf("expected mode %v, got: %v", referenceInfo.Mode(), info.Mode())
+	}
+}
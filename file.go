@@ -2,15 +2,19 @@ package fs_utils
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"strings"
 )
 
 // File is a structure with information about file.
 // File should be initialized by functions
-// CreateFileQ or CreateFileW.
+// CreateFileQ, CreateFileW or ReadFileQ.
 // Can be removed by RemoveFileW or RemoveFileA.
+// Content can be written back by Save.
 type File struct {
 	Path    string
 	Content FileLines
@@ -24,6 +28,7 @@ type FileLines []string
 func emptyFileW(f *File) {
 	f.Path = ""
 	f.Content = nil
+	f.setStamp(fileStamp{})
 }
 
 // emptyFileQ makes property f empty
@@ -32,6 +37,7 @@ func emptyFileQ(f *File) FileLines {
 	lastContent := f.Content
 	f.Path = ""
 	f.Content = nil
+	f.setStamp(fileStamp{})
 	return lastContent
 }
 
@@ -50,23 +56,62 @@ func GetFile(path string) (string, error) {
 	return path, nil
 }
 
-// CreateFileQ creates a file at a specific path.
+// existsError is returned when file is created
+// at a path which is already taken.
+type existsError struct {
+	path string
+}
+
+func (e *existsError) Error() string {
+	return fmt.Sprintf("file %v already exists", e.path)
+}
+
+// Is makes errors.Is(err, fs.ErrExist) true.
+func (e *existsError) Is(target error) bool {
+	return target == fs.ErrExist
+}
+
+// createExclusive creates a file with O_CREATE|O_EXCL,
+// so checking existence and creation is one atomic step.
+// File gets mode from perm, which is verified before returning.
 // If the file already exists, then returns an error.
-func CreateFileQ(path string) (*File, error) {
-	if IsFileExists(path) {
-		return nil, fmt.Errorf("file %v already exists", path)
+func createExclusive(path string, perm Perm) (*os.File, os.FileMode, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm.File)
+	if os.IsExist(err) {
+		return nil, 0, &existsError{path}
 	}
-
-	file, err := os.Create(path)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	defer func(file *os.File) {
+	mode, err := applyFilePerm(file, perm)
+	if err != nil {
 		_ = file.Close()
-	}(file)
+		_ = os.Remove(path)
+		return nil, mode, err
+	}
 
-	return &File{path, []string{""}}, nil
+	return file, mode, nil
+}
+
+// applyFilePerm sets mode of created file if umask is ignored,
+// then verifies it.
+func applyFilePerm(file *os.File, perm Perm) (os.FileMode, error) {
+	return applyModeFD(file, perm.File, perm.IgnoreUmask)
+}
+
+// createExclusiveW creates a file with createExclusive
+// and writes content to the file.
+func createExclusiveW(path string, content FileLines) error {
+	_, _, err := CreateFileWPerm(path, content, DefaultPerm)
+	return err
+}
+
+// CreateFileQ creates a file at a specific path.
+// If the file already exists, then returns an error.
+func CreateFileQ(path string) (*File, error) {
+	f, _, err := CreateFileQPerm(path, DefaultPerm)
+	return f, err
 }
 
 // CreateFileW creates a file at a specific path,
@@ -74,31 +119,11 @@ func CreateFileQ(path string) (*File, error) {
 // Every element of content is a new line.
 // If the file already exists, then returns an error.
 func CreateFileW(path string, content FileLines) (*File, error) {
-	if IsFileExists(path) {
-		return nil, fmt.Errorf("file %v already exists", path)
-	}
-
-	file, err := os.Create(path)
-	if err != nil {
+	if err := createExclusiveW(path, content); err != nil {
 		return nil, err
 	}
 
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(file)
-
-	writer := bufio.NewWriter(file)
-	defer func(writer *bufio.Writer) {
-		_ = writer.Flush()
-	}(writer)
-
-	for _, v := range content {
-		if _, err := writer.WriteString(v + "\n"); err != nil {
-			return nil, err
-		}
-	}
-
-	return &File{path, content}, nil
+	return newFile(path, content), nil
 }
 
 // CreateFileA creates a file at a specific path,
@@ -106,51 +131,14 @@ func CreateFileW(path string, content FileLines) (*File, error) {
 // Every element of content is a new line.
 // If the file already exists, then returns an error.
 func CreateFileA(path string, content FileLines) error {
-	if IsFileExists(path) {
-		return fmt.Errorf("file %v already exists", path)
-	}
-
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(file)
-
-	writer := bufio.NewWriter(file)
-	defer func(writer *bufio.Writer) {
-		_ = writer.Flush()
-	}(writer)
-
-	for _, line := range content {
-		_, err = writer.WriteString(line + "\n")
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return createExclusiveW(path, content)
 }
 
 // CreateFileR creates a file at a specific path.
 // If the file already exists, then returns an error.
 func CreateFileR(path string) error {
-	if IsFileExists(path) {
-		return fmt.Errorf("file %v already exists", path)
-	}
-
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(file)
-
-	return nil
+	_, err := CreateFileRPerm(path, DefaultPerm)
+	return err
 }
 
 // RemoveFileQ removes a file at a specific path.
@@ -205,35 +193,23 @@ func RemoveFileA(f *File) (FileLines, error) {
 //
 // 2. BYE!
 //
+// Binary files are output as they are.
 // If there's error, function panics.
+// Use FprintFileContent to get the error, refuse binary files,
+// or write it to other io.Writer or in other format.
 func OutputFileContent(path string) {
-	file, err := os.Open(path)
-	if err != nil {
+	if err := fprintFile(os.Stdout, path, DefaultLineFormat); err != nil {
 		panic(err)
 	}
-
-	defer func(file *os.File) {
-		err := file.Close()
-		if err != nil {
-			panic(err)
-		}
-	}(file)
-
-	scanner := bufio.NewScanner(file)
-
-	i := 1
-	for scanner.Scan() {
-		fmt.Printf("%v. %v\n", i, scanner.Text())
-		i++
-	}
 }
 
 // GetFileContent returns slice of content from specific file.
 // Every element of slice marked as one line.
+// Binary files are read as text, use GetTextFileContent to refuse them.
+// Lines longer than 64KB aren't supported, then bufio.ErrTooLong
+// is returned instead of truncated content. Use ReadFileQ for such files.
 // If there's an error, function returns nil and error.
 func GetFileContent(path string) (FileLines, error) {
-	var lines FileLines
-
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -243,19 +219,50 @@ func GetFileContent(path string) (FileLines, error) {
 		_ = file.Close()
 	}(file)
 
-	scanner := bufio.NewScanner(file)
+	return scanLines(file)
+}
+
+// scanLines reads lines from r.
+func scanLines(r io.Reader) (FileLines, error) {
+	var lines FileLines
+	scanner := bufio.NewScanner(r)
 
 	for scanner.Scan() {
 		lines = append(lines, scanner.Text())
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 
 	return lines, nil
 }
 
+// splitLines splits text to lines without line endings.
+// Last empty line after trailing newline isn't included.
+func splitLines(text string) FileLines {
+	if text == "" {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
 // WriteContent writes content to file.
+// Previous content of file is replaced.
 // If it couldn't, returns error.
 func WriteContent(path string, content FileLines) error {
-	file, err := os.Open(path)
+	return writeContent(context.Background(), path, content, false)
+}
+
+// writeContent replaces content of file.
+// If lock is set, takes exclusive lock before truncating the file
+// and holds it until content is written.
+func writeContent(ctx context.Context, path string, content FileLines, lock bool) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
 	if err != nil {
 		return err
 	}
@@ -264,33 +271,29 @@ func WriteContent(path string, content FileLines) error {
 		_ = file.Close()
 	}(file)
 
-	writer := bufio.NewWriter(file)
-
-	defer func(writer *bufio.Writer) {
-		_ = writer.Flush()
-	}(writer)
-
-	for _, line := range content {
-		_, err = writer.WriteString(line + "\n")
-		if err != nil {
+	if lock {
+		if err := waitLock(ctx, file, false); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+
+	return writeLines(file, content)
 }
 
 // Output outputs lines.
 // If there aren't lines, outputs error.
+// Use Fprint to write them to other io.Writer or in other format.
 func (fl FileLines) Output() {
 	if len(fl) == 0 {
 		fmt.Printf("FileLines.Output: there aren't lines\n")
 		return
 	}
 
-	for i := 0; i < len(fl); i++ {
-		fmt.Printf("%v. %v\n", i+1, fl[i])
-	}
+	_ = fl.Fprint(os.Stdout, DefaultLineFormat)
 }
 
 // RenameFile renames a file from oldPath to newPath.
@@ -305,29 +308,40 @@ func RenameFile(oldPath, newPath string) error {
 // CopyFile copies a file from source to destination.
 // If the destination file already exists, returns an error.
 func CopyFile(source, destination string) error {
-	if IsFileExists(destination) {
-		return fmt.Errorf("file %v already exists", destination)
-	}
+	_, err := copyFile(source, destination, DefaultPerm)
+	return err
+}
 
+// copyFile copies a file from source to destination,
+// which is created with mode from perm.
+func copyFile(source, destination string, perm Perm) (os.FileMode, error) {
 	input, err := os.Open(source)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer input.Close()
 
-	output, err := os.Create(destination)
+	output, mode, err := createExclusive(destination, perm)
 	if err != nil {
-		return err
+		return mode, err
 	}
-	defer output.Close()
 
 	_, err = io.Copy(output, input)
-	return err
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	return mode, err
 }
 
 // AppendToFile appends content to an existing file.
 // If the file doesn't exist, returns an error.
 func AppendToFile(path string, content FileLines) error {
+	return appendContent(context.Background(), path, content, false)
+}
+
+// appendContent appends content to an existing file.
+// If lock is set, holds exclusive lock while content is written.
+func appendContent(ctx context.Context, path string, content FileLines, lock bool) error {
 	if !IsFileExists(path) {
 		return fmt.Errorf("file %v does not exist", path)
 	}
@@ -338,15 +352,25 @@ func AppendToFile(path string, content FileLines) error {
 	}
 	defer file.Close()
 
-	writer := bufio.NewWriter(file)
-	defer writer.Flush()
+	if lock {
+		if err := waitLock(ctx, file, false); err != nil {
+			return err
+		}
+	}
+
+	return writeLines(file, content)
+}
+
+// writeLines writes content to w.
+// Every element of content is a new line.
+func writeLines(w io.Writer, content FileLines) error {
+	writer := bufio.NewWriter(w)
 
 	for _, line := range content {
-		_, err = writer.WriteString(line + "\n")
-		if err != nil {
+		if _, err := writer.WriteString(line + "\n"); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return writer.Flush()
 }
@@ -0,0 +1,92 @@
+package fs_utils
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestCreateFileW(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.txt")
+
+	// Test creating a new file
+	f, err := CreateFileW(path, FileLines{"HI!", "BYE!"})
+	if err != nil {
+		t.Fatalf("expected to create file: %v, error: %v", path, err)
+	}
+	if f.Path != path {
+		t.Errorf("expected file path to be: %v, got: %v", path, f.Path)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != "HI!\nBYE!\n" {
+		t.Errorf("expected file content to be written, got: %q, error: %v", content, err)
+	}
+
+	// Test creating an existing file
+	_, err = CreateFileW(path, FileLines{"other"})
+	if !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected exists error, got: %v", err)
+	}
+
+	// Verify existing file wasn't truncated
+	content, _ = os.ReadFile(path)
+	if string(content) != "HI!\nBYE!\n" {
+		t.Errorf("expected file to be untouched, got: %q", content)
+	}
+}
+
+func TestCreateFileConcurrent(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "race.txt")
+
+	const n = 32
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+
+	// Test creating the same file from many goroutines
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := CreateFileA(path, FileLines{"winner"}); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			} else if !errors.Is(err, fs.ErrExist) {
+				t.Errorf("expected exists error, got: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("expected exactly one goroutine to succeed, got: %v", succeeded)
+	}
+}
+
+func TestCopyFileDestinationExists(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "source.txt")
+	destination := filepath.Join(tempDir, "destination.txt")
+
+	os.WriteFile(source, []byte("source"), 0644)
+	os.WriteFile(destination, []byte("destination"), 0644)
+
+	// Test copying to existing destination
+	err := CopyFile(source, destination)
+	if !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected exists error, got: %v", err)
+	}
+
+	// Verify destination wasn't overwritten
+	content, _ := os.ReadFile(destination)
+	if string(content) != "destination" {
+		t.Errorf("expected destination to be untouched, got: %q", content)
+	}
+}
@@ -0,0 +1,173 @@
+package fs_utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrRemoveRefused is returned when a guard refuses recursive removal.
+var ErrRemoveRefused = errors.New("removal refused by guard")
+
+// ProtectedPaths are paths which RemoveDirQ refuses to remove,
+// together with every directory above them.
+// Can be extended by caller.
+var ProtectedPaths = []string{
+	"/bin", "/boot", "/dev", "/etc", "/home", "/lib", "/lib64", "/opt",
+	"/proc", "/root", "/sbin", "/sys", "/tmp", "/usr", "/var",
+}
+
+// RemoveGuard contains safety checks which run
+// before recursive removal starts.
+// Empty path and filesystem root are always refused.
+// Every other check can be disabled only by its Allow* option.
+type RemoveGuard struct {
+	// Protected are paths which can't be removed, in addition to ProtectedPaths.
+	// Directories above them can't be removed too.
+	Protected []string
+	// MaxEntries is maximum count of files and directories to remove.
+	// If it's 0, count isn't limited.
+	MaxEntries int
+	// MaxBytes is maximum total size of files to remove.
+	// If it's 0, size isn't limited.
+	MaxBytes int64
+
+	// AllowHome allows removal of home directory and directories above it.
+	AllowHome bool
+	// AllowMountPoint allows removal of mount points.
+	AllowMountPoint bool
+	// AllowProtected allows removal of protected paths.
+	AllowProtected bool
+}
+
+// DefaultRemoveGuard is guard used by RemoveDirQ.
+var DefaultRemoveGuard = RemoveGuard{}
+
+// GuardError describes why guard refused removal.
+type GuardError struct {
+	Path   string
+	Reason string
+}
+
+func (e *GuardError) Error() string {
+	return fmt.Sprintf("refusing to remove %v: %v", e.Path, e.Reason)
+}
+
+// Unwrap returns ErrRemoveRefused.
+func (e *GuardError) Unwrap() error {
+	return ErrRemoveRefused
+}
+
+// CheckRemove runs guard checks for path without removing anything.
+// If removal should be refused, returns *GuardError.
+func CheckRemove(path string, guard RemoveGuard) error {
+	if strings.TrimSpace(path) == "" {
+		return &GuardError{path, "empty path"}
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	candidates := []string{absPath}
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil && resolved != absPath {
+		candidates = append(candidates, resolved)
+	}
+
+	for _, candidate := range candidates {
+		if filepath.Dir(candidate) == candidate {
+			return &GuardError{path, "filesystem root"}
+		}
+
+		if !guard.AllowHome {
+			if home, err := os.UserHomeDir(); err == nil && isPathAbove(candidate, home) {
+				return &GuardError{path, "home directory " + home}
+			}
+		}
+
+		if !guard.AllowProtected {
+			protectedPaths := append(append([]string{}, guard.Protected...), ProtectedPaths...)
+			for _, protected := range protectedPaths {
+				if isPathAbove(candidate, protected) {
+					return &GuardError{path, "protected path " + protected}
+				}
+			}
+		}
+
+		if !guard.AllowMountPoint && isMountPoint(candidate) {
+			return &GuardError{path, "mount point"}
+		}
+	}
+
+	if guard.MaxEntries > 0 || guard.MaxBytes > 0 {
+		return checkRemoveLimits(path, guard)
+	}
+
+	return nil
+}
+
+// isPathAbove reports whether path is equal to target
+// or is one of its parents.
+func isPathAbove(path, target string) bool {
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(path, target)
+	if err != nil {
+		return false
+	}
+
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// isMountPoint reports whether directory is located
+// on other device than its parent.
+func isMountPoint(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	parentInfo, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+
+	device, ok := fileDevice(info)
+	parentDevice, parentOk := fileDevice(parentInfo)
+	return ok && parentOk && device != parentDevice
+}
+
+// checkRemoveLimits counts entries and bytes under path
+// and stops as soon as limit is exceeded.
+func checkRemoveLimits(path string, guard RemoveGuard) error {
+	var entries int
+	var size int64
+
+	err := filepath.Walk(path, func(location string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		entries++
+		if !info.IsDir() {
+			size += info.Size()
+		}
+
+		if guard.MaxEntries > 0 && entries > guard.MaxEntries {
+			return &GuardError{path, fmt.Sprintf("more than %v entries", guard.MaxEntries)}
+		}
+		if guard.MaxBytes > 0 && size > guard.MaxBytes {
+			return &GuardError{path, fmt.Sprintf("more than %v bytes", guard.MaxBytes)}
+		}
+
+		return nil
+	})
+
+	return err
+}
@@ -0,0 +1,92 @@
+package fs_utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckRemoveRefused(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	paths := []string{"", "/", home, filepath.Dir(home), "/usr", "/usr/"}
+	for _, path := range paths {
+		err := CheckRemove(path, RemoveGuard{})
+		if !errors.Is(err, ErrRemoveRefused) {
+			t.Errorf("expected removal of %q to be refused, got: %v", path, err)
+		}
+	}
+
+	// Test explicit override
+	if err := CheckRemove(home, RemoveGuard{AllowHome: true}); err != nil {
+		t.Errorf("expected removal of home to be allowed, error: %v", err)
+	}
+}
+
+func TestCheckRemoveProtected(t *testing.T) {
+	tempDir := t.TempDir()
+	protected := filepath.Join(tempDir, "data", "keep")
+	os.MkdirAll(protected, os.ModePerm)
+
+	guard := RemoveGuard{Protected: []string{protected}}
+
+	// Test removing protected path and its parent
+	for _, path := range []string{protected, filepath.Join(tempDir, "data")} {
+		if err := CheckRemove(path, guard); !errors.Is(err, ErrRemoveRefused) {
+			t.Errorf("expected removal of %v to be refused, got: %v", path, err)
+		}
+	}
+
+	// Test removing path below protected one
+	if err := CheckRemove(filepath.Join(protected, "sub"), guard); err != nil {
+		t.Errorf("expected removal below protected path to be allowed, error: %v", err)
+	}
+
+	// Test explicit override
+	guard.AllowProtected = true
+	if err := CheckRemove(protected, guard); err != nil {
+		t.Errorf("expected removal of protected path to be allowed, error: %v", err)
+	}
+}
+
+func TestRemoveDirGuardedLimits(t *testing.T) {
+	tempDir := t.TempDir()
+	dir := filepath.Join(tempDir, "dir")
+	os.Mkdir(dir, os.ModePerm)
+	os.WriteFile(filepath.Join(dir, "file1.txt"), []byte("test"), 0644)
+	os.WriteFile(filepath.Join(dir, "file2.txt"), []byte("test"), 0644)
+
+	// Test entry limit
+	err := RemoveDirGuarded(dir, RemoveGuard{MaxEntries: 2})
+	if !errors.Is(err, ErrRemoveRefused) {
+		t.Errorf("expected removal to be refused by entry limit, got: %v", err)
+	}
+
+	// Test byte limit
+	err = RemoveDirGuarded(dir, RemoveGuard{MaxBytes: 7})
+	if !errors.Is(err, ErrRemoveRefused) {
+		t.Errorf("expected removal to be refused by byte limit, got: %v", err)
+	}
+
+	// Verify nothing was removed
+	if !IsFileExists(filepath.Join(dir, "file2.txt")) {
+		t.Errorf("expected files to stay after refused removal")
+	}
+
+	// Test removal within limits
+	if err := RemoveDirGuarded(dir, RemoveGuard{MaxEntries: 3, MaxBytes: 8}); err != nil {
+		t.Errorf("expected to remove directory: %v, error: %v", dir, err)
+	}
+	if IsDirExists(dir) {
+		t.Errorf("expected directory to not exist: %v", dir)
+	}
+}
+
+func TestRemoveDirQEmptyPath(t *testing.T) {
+	// Test removing empty path, which used to become "/"
+	if err := RemoveDirQ(""); !errors.Is(err, ErrRemoveRefused) {
+		t.Errorf("expected removal of empty path to be refused, got: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package fs_utils
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// HashAlgorithm is a name of checksum algorithm.
+type HashAlgorithm string
+
+const (
+	MD5    HashAlgorithm = "md5"
+	SHA1   HashAlgorithm = "sha1"
+	SHA256 HashAlgorithm = "sha256"
+	SHA512 HashAlgorithm = "sha512"
+	CRC32  HashAlgorithm = "crc32"
+)
+
+// hashBuffers are reused by HashReader.
+var hashBuffers = sync.Pool{
+	New: func() any {
+		buffer := make([]byte, 64*1024)
+		return &buffer
+	},
+}
+
+// New returns new hash of the algorithm.
+// If algorithm is unknown, returns an error.
+func (a HashAlgorithm) New() (hash.Hash, error) {
+	switch a {
+	case MD5:
+		return md5.New(), nil
+	case SHA1:
+		return sha1.New(), nil
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	case CRC32:
+		return crc32.NewIEEE(), nil
+	}
+	return nil, fmt.Errorf("unknown hash algorithm %v", a)
+}
+
+// HashReader returns hex-encoded checksum of content of r.
+func HashReader(r io.Reader, algo HashAlgorithm) (string, error) {
+	sum, err := hashReader(r, algo)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// hashReader returns checksum of content of r.
+func hashReader(r io.Reader, algo HashAlgorithm) ([]byte, error) {
+	h, err := algo.New()
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := hashBuffers.Get().(*[]byte)
+	defer hashBuffers.Put(buffer)
+
+	if _, err := io.CopyBuffer(h, r, *buffer); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// HashFile returns hex-encoded checksum of file content,
+// same as sha256sum and similar tools print.
+func HashFile(path string, algo HashAlgorithm) (string, error) {
+	sum, err := hashFile(path, algo)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// hashFile returns checksum of file content.
+func hashFile(path string, algo HashAlgorithm) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return hashReader(file, algo)
+}
+
+// HashDirOptions configures HashDir.
+type HashDirOptions struct {
+	// Algorithm is SHA256, if it isn't set.
+	Algorithm HashAlgorithm
+	// Include and Exclude are glob patterns, matched against
+	// slash-separated path relative to root and against base name.
+	// If Include is set, only matching files are hashed.
+	// Excluded directories are skipped with their content.
+	Include []string
+	Exclude []string
+}
+
+// HashDir returns hex-encoded Merkle-style checksum of directory tree.
+// Checksum of directory covers names, types and modes of its entries,
+// checksums of files and targets of symlinks.
+// Name and mode of root itself aren't covered, so copies
+// of directory at different locations have same checksum.
+func HashDir(root string, opts HashDirOptions) (string, error) {
+	if opts.Algorithm == "" {
+		opts.Algorithm = SHA256
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%v is not a directory", root)
+	}
+
+	sum, err := hashDir(root, "", opts)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// hashDir returns checksum of directory at root/rel.
+func hashDir(root, rel string, opts HashDirOptions) ([]byte, error) {
+	entries, err := os.ReadDir(filepath.Join(root, rel))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	h, err := opts.Algorithm.New()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		entryRel := filepath.Join(rel, entry.Name())
+		if matchAny(opts.Exclude, entryRel) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		location := filepath.Join(root, entryRel)
+
+		var sum []byte
+		switch {
+		case info.IsDir():
+			sum, err = hashDir(root, entryRel, opts)
+		case info.Mode()&os.ModeSymlink != 0:
+			var link string
+			link, err = os.Readlink(location)
+			sum = []byte(filepath.ToSlash(link))
+		case info.Mode().IsRegular():
+			if len(opts.Include) > 0 && !matchAny(opts.Include, entryRel) {
+				continue
+			}
+			sum, err = hashFile(location, opts.Algorithm)
+		default:
+			// Devices, sockets and pipes don't have content.
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		writeHashEntry(h, entry.Name(), info.Mode(), sum)
+	}
+
+	return h.Sum(nil), nil
+}
+
+// writeHashEntry writes entry of directory to h.
+// Lengths are written before variable parts,
+// so different entries never produce same bytes.
+func writeHashEntry(h hash.Hash, name string, mode os.FileMode, sum []byte) {
+	var header [12]byte
+	binary.BigEndian.PutUint32(header[0:], uint32(mode.Type()|mode.Perm()))
+	binary.BigEndian.PutUint32(header[4:], uint32(len(name)))
+	binary.BigEndian.PutUint32(header[8:], uint32(len(sum)))
+	h.Write(header[:])
+	io.WriteString(h, name)
+	h.Write(sum)
+}
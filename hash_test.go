@@ -0,0 +1,85 @@
+package fs_utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestHashFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.txt")
+	os.WriteFile(path, []byte("hello\n"), 0644)
+
+	tests := map[HashAlgorithm]string{
+		MD5:    "b1946ac92492d2347c6235b4d2611184",
+		SHA1:   "f572d396fae9206628714fb2ce00f72e94f2258f",
+		SHA256: "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+		SHA512: "e7c22b994c59d9cf2b48e549b1e24666636045930d3da7c1acb299d1c3b7f931f94aae41edda2c2b207a36e10f8bcb8d45223e54878f5b316e7ce3b6bc019629",
+		CRC32:  "363a3020",
+	}
+	for algo, expected := range tests {
+		sum, err := HashFile(path, algo)
+		if err != nil {
+			t.Errorf("expected to hash file with %v, error: %v", algo, err)
+		}
+		if sum != expected {
+			t.Errorf("expected %v checksum: %v, got: %v", algo, expected, sum)
+		}
+	}
+
+	if _, err := HashFile(path, "sha3"); err == nil {
+		t.Errorf("expected error for unknown algorithm")
+	}
+}
+
+func TestHashDir(t *testing.T) {
+	tempDir := t.TempDir()
+	first := filepath.Join(tempDir, "first")
+	second := filepath.Join(tempDir, "second")
+	for _, dir := range []string{first, second} {
+		os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+		os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644)
+		os.WriteFile(filepath.Join(dir, "sub", "b.txt"), []byte("b"), 0644)
+	}
+
+	hash := func(dir string, opts HashDirOptions) string {
+		sum, err := HashDir(dir, opts)
+		if err != nil {
+			t.Fatalf("expected to hash directory: %v, error: %v", dir, err)
+		}
+		return sum
+	}
+
+	// Test equal trees
+	if hash(first, HashDirOptions{}) != hash(second, HashDirOptions{}) {
+		t.Errorf("expected equal trees to have same checksum")
+	}
+
+	// Test changed content
+	os.WriteFile(filepath.Join(second, "sub", "b.txt"), []byte("B"), 0644)
+	if hash(first, HashDirOptions{}) == hash(second, HashDirOptions{}) {
+		t.Errorf("expected changed content to change checksum")
+	}
+
+	// Test excluded files
+	opts := HashDirOptions{Exclude: []string{"sub"}}
+	if hash(first, opts) != hash(second, opts) {
+		t.Errorf("expected excluded directory not to change checksum")
+	}
+
+	// Test renamed file
+	os.Rename(filepath.Join(second, "a.txt"), filepath.Join(second, "c.txt"))
+	if hash(first, opts) == hash(second, opts) {
+		t.Errorf("expected renamed file to change checksum")
+	}
+	os.Rename(filepath.Join(second, "c.txt"), filepath.Join(second, "a.txt"))
+
+	// Test changed mode
+	if runtime.GOOS != "windows" {
+		os.Chmod(filepath.Join(second, "a.txt"), 0600)
+		if hash(first, opts) == hash(second, opts) {
+			t.Errorf("expected changed mode to change checksum")
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package fs_utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// lineIndexMagic starts sidecar file of LineIndex.
+const lineIndexMagic = "LIDX0001"
+
+// LineIndexSuffix is appended to path of file to get path of its index.
+const LineIndexSuffix = ".lidx"
+
+// ErrStaleIndex is returned when file was changed after its index was built.
+var ErrStaleIndex = errors.New("line index is stale")
+
+// lineIndexCacheSize is maximum number of indexes kept by GetLineIndex.
+const lineIndexCacheSize = 64
+
+// lineIndexEntry is cached index of single file.
+// Its mutex is held while index is built,
+// so other files aren't blocked meanwhile.
+type lineIndexEntry struct {
+	sync.Mutex
+	index *LineIndex
+	used  uint64
+}
+
+// lineIndexCache keeps indexes returned by GetLineIndex,
+// so file isn't read again on every call.
+// Least recently used entry is evicted, when cache is full.
+var lineIndexCache = struct {
+	sync.Mutex
+	entries map[string]*lineIndexEntry
+	clock   uint64
+}{entries: make(map[string]*lineIndexEntry)}
+
+// LineIndex contains offsets of lines of file,
+// so any line can be read without reading lines before it.
+// Size and ModTime of file are kept to detect its changes.
+// Should be initialized by BuildLineIndex, LoadLineIndex or GetLineIndex.
+type LineIndex struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Offsets []int64
+}
+
+// BuildLineIndex reads file once and records offsets of its lines.
+func BuildLineIndex(path string) (*LineIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	index := &LineIndex{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+	if info.Size() > 0 {
+		index.Offsets = append(index.Offsets, 0)
+	}
+
+	buffer := make([]byte, 64*1024)
+	var position int64
+	for {
+		n, err := file.Read(buffer)
+		chunk := buffer[:n]
+		for start := 0; ; {
+			i := bytes.IndexByte(chunk[start:], '\n')
+			if i < 0 {
+				break
+			}
+			start += i + 1
+			if next := position + int64(start); next < index.Size {
+				index.Offsets = append(index.Offsets, next)
+			}
+		}
+		position += int64(n)
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return index, nil
+}
+
+// LoadLineIndex loads index of file at path from its sidecar file.
+// If file was changed since index was built, returns ErrStaleIndex.
+func LoadLineIndex(path string) (*LineIndex, error) {
+	data, err := os.ReadFile(path + LineIndexSuffix)
+	if err != nil {
+		return nil, err
+	}
+
+	index, err := decodeLineIndex(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid line index of %v: %w", path, err)
+	}
+	index.Path = path
+
+	if !index.Valid() {
+		return nil, fmt.Errorf("%v: %w", path, ErrStaleIndex)
+	}
+
+	return index, nil
+}
+
+// GetLineIndex loads index of file at path from its sidecar file,
+// or builds it if sidecar doesn't exist, is invalid or stale.
+// Sidecar file isn't written, call Save to keep built index.
+// Index is cached until file changes, so returned index
+// is shared between calls and shouldn't be modified.
+func GetLineIndex(path string) (*LineIndex, error) {
+	entry := cachedLineIndex(path)
+	entry.Lock()
+	defer entry.Unlock()
+
+	if entry.index != nil && entry.index.Valid() {
+		return entry.index, nil
+	}
+
+	index, err := LoadLineIndex(path)
+	if err != nil {
+		if index, err = BuildLineIndex(path); err != nil {
+			entry.index = nil
+			return nil, err
+		}
+	}
+
+	entry.index = index
+	return index, nil
+}
+
+// rebuildLineIndex builds index of file at path again
+// and replaces cached one.
+func rebuildLineIndex(path string) (*LineIndex, error) {
+	entry := cachedLineIndex(path)
+	entry.Lock()
+	defer entry.Unlock()
+
+	index, err := BuildLineIndex(path)
+	if err != nil {
+		entry.index = nil
+		return nil, err
+	}
+
+	entry.index = index
+	return index, nil
+}
+
+// cachedLineIndex returns cache entry of path, adding it if needed.
+func cachedLineIndex(path string) *lineIndexEntry {
+	lineIndexCache.Lock()
+	defer lineIndexCache.Unlock()
+
+	lineIndexCache.clock++
+	entry, ok := lineIndexCache.entries[path]
+	if !ok {
+		if len(lineIndexCache.entries) >= lineIndexCacheSize {
+			evictLineIndex()
+		}
+		entry = &lineIndexEntry{}
+		lineIndexCache.entries[path] = entry
+	}
+	entry.used = lineIndexCache.clock
+	return entry
+}
+
+// evictLineIndex removes least recently used entry from cache.
+// Should be called with cache locked.
+func evictLineIndex() {
+	var oldest string
+	var used uint64
+	for path, entry := range lineIndexCache.entries {
+		if oldest == "" || entry.used < used {
+			oldest, used = path, entry.used
+		}
+	}
+	delete(lineIndexCache.entries, oldest)
+}
+
+// Valid reports whether file has same size and modification time,
+// as it had when index was built.
+func (idx *LineIndex) Valid() bool {
+	info, err := os.Stat(idx.Path)
+	return err == nil && info.Size() == idx.Size && info.ModTime().Equal(idx.ModTime)
+}
+
+// Lines returns number of lines in file.
+func (idx *LineIndex) Lines() int {
+	return len(idx.Offsets)
+}
+
+// Save writes index to sidecar file next to the file.
+func (idx *LineIndex) Save() error {
+	info, err := os.Stat(idx.Path)
+	if err != nil {
+		return err
+	}
+
+	return writeAtomic(idx.Path+LineIndexSuffix, info.Mode().Perm(), func(w io.Writer) error {
+		writer := bufio.NewWriter(w)
+		writer.WriteString(lineIndexMagic)
+
+		buffer := make([]byte, binary.MaxVarintLen64)
+		put := func(n uint64) {
+			writer.Write(buffer[:binary.PutUvarint(buffer, n)])
+		}
+
+		put(uint64(idx.Size))
+		put(uint64(idx.ModTime.UnixNano()))
+		put(uint64(len(idx.Offsets)))
+		var previous int64
+		for _, offset := range idx.Offsets {
+			put(uint64(offset - previous))
+			previous = offset
+		}
+
+		return writer.Flush()
+	})
+}
+
+// decodeLineIndex decodes sidecar file, which was written by Save.
+func decodeLineIndex(data []byte) (*LineIndex, error) {
+	if !bytes.HasPrefix(data, []byte(lineIndexMagic)) {
+		return nil, fmt.Errorf("unknown format")
+	}
+	reader := bytes.NewReader(data[len(lineIndexMagic):])
+
+	var values [3]uint64
+	for i := range values {
+		value, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	size, count := values[0], values[2]
+	if size > math.MaxInt64 {
+		return nil, fmt.Errorf("invalid size %v", size)
+	}
+	// Every line takes at least one byte of file and of index.
+	if count > size || count > uint64(reader.Len()) || (count == 0) != (size == 0) {
+		return nil, fmt.Errorf("invalid number of lines %v", count)
+	}
+
+	index := &LineIndex{
+		Size:    int64(size),
+		ModTime: time.Unix(0, int64(values[1])),
+		Offsets: make([]int64, count),
+	}
+	var offset uint64
+	for i := range index.Offsets {
+		delta, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return nil, err
+		}
+		// Offsets start at 0, grow and stay inside of file.
+		if (i == 0) != (delta == 0) || delta >= size-offset {
+			return nil, fmt.Errorf("invalid offset of line %v", i)
+		}
+		offset += delta
+		index.Offsets[i] = int64(offset)
+	}
+	if reader.Len() > 0 {
+		return nil, fmt.Errorf("unexpected data after offsets")
+	}
+
+	return index, nil
+}
+
+// ReadLines returns lines from `from` to `to` (exclusive).
+// Lines are indexed from 0, to is limited by number of lines.
+// If file was changed since index was built,
+// or read lines don't start and end at newlines, returns ErrStaleIndex.
+func (idx *LineIndex) ReadLines(from, to int) (FileLines, error) {
+	to = min(to, idx.Lines())
+	if from < 0 || from > to {
+		return nil, fmt.Errorf("invalid range [%v, %v) of %v lines", from, to, idx.Lines())
+	}
+	if from == to {
+		return nil, nil
+	}
+	if !idx.Valid() {
+		return nil, fmt.Errorf("%v: %w", idx.Path, ErrStaleIndex)
+	}
+
+	end := idx.Size
+	if to < idx.Lines() {
+		end = idx.Offsets[to]
+	}
+
+	file, err := os.Open(idx.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Newline before first line is read too, to check offsets.
+	start := max(0, idx.Offsets[from]-1)
+	data := make([]byte, end-start)
+	if _, err := file.ReadAt(data, start); err != nil {
+		return nil, err
+	}
+	if from > 0 {
+		if data[0] != '\n' {
+			return nil, fmt.Errorf("%v: %w", idx.Path, ErrStaleIndex)
+		}
+		data = data[1:]
+	}
+	if to < idx.Lines() && data[len(data)-1] != '\n' {
+		return nil, fmt.Errorf("%v: %w", idx.Path, ErrStaleIndex)
+	}
+
+	return splitLines(string(data)), nil
+}
+
+// ReadLines returns lines from `from` to `to` (exclusive) of file at path.
+// Lines are indexed from 0.
+// Uses line index of file, which is built on first use
+// and rebuilt when it doesn't match file.
+// Index is only kept in memory, see GetLineIndex.
+func ReadLines(path string, from, to int) (FileLines, error) {
+	index, err := GetLineIndex(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := index.ReadLines(from, to)
+	if errors.Is(err, ErrStaleIndex) {
+		if index, err = rebuildLineIndex(path); err != nil {
+			return nil, err
+		}
+		return index.ReadLines(from, to)
+	}
+	return lines, err
+}
@@ -0,0 +1,120 @@
+package fs_utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+
+	var builder strings.Builder
+	for i := 0; i < 50000; i++ {
+		fmt.Fprintf(&builder, "line %v\r\n", i)
+	}
+	builder.WriteString("\nlast")
+	os.WriteFile(path, []byte(builder.String()), 0644)
+
+	lines, err := ReadLines(path, 40000, 40003)
+	if err != nil {
+		t.Fatalf("expected to read lines of: %v, error: %v", path, err)
+	}
+	if !reflect.DeepEqual(lines, FileLines{"line 40000", "line 40001", "line 40002"}) {
+		t.Errorf("expected lines 40000-40002, got: %v", lines)
+	}
+	if lines, _ := ReadLines(path, 50000, 100000); !reflect.DeepEqual(lines, FileLines{"", "last"}) {
+		t.Errorf("expected last lines, got: %q", lines)
+	}
+
+	// Test saving index
+	if IsFileExists(path + LineIndexSuffix) {
+		t.Errorf("expected sidecar file not to be written by ReadLines")
+	}
+	index, err := GetLineIndex(path)
+	if err != nil {
+		t.Fatalf("expected to get index, error: %v", err)
+	}
+	if err := index.Save(); err != nil {
+		t.Fatalf("expected to save index, error: %v", err)
+	}
+	index, err = LoadLineIndex(path)
+	if err != nil {
+		t.Fatalf("expected to load saved index, error: %v", err)
+	}
+	if index.Lines() != 50002 {
+		t.Errorf("expected 50002 lines, got: %v", index.Lines())
+	}
+
+	// Test invalidation
+	AppendToFile(path, FileLines{"appended"})
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(path, future, future)
+
+	if _, err := index.ReadLines(0, 1); !errors.Is(err, ErrStaleIndex) {
+		t.Errorf("expected ErrStaleIndex, got: %v", err)
+	}
+	if _, err := LoadLineIndex(path); !errors.Is(err, ErrStaleIndex) {
+		t.Errorf("expected ErrStaleIndex, got: %v", err)
+	}
+	if lines, _ := ReadLines(path, 50001, 50003); !reflect.DeepEqual(lines, FileLines{"lastappended"}) {
+		t.Errorf("expected index to be rebuilt, got: %q", lines)
+	}
+}
+
+// Test rebuilding of invalid sidecar file
+func TestLineIndexInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0644)
+	info, _ := os.Stat(path)
+
+	// Offsets outside of file
+	index := &LineIndex{Path: path, Size: info.Size(), ModTime: info.ModTime(), Offsets: []int64{0, 100}}
+	index.Save()
+	if _, err := LoadLineIndex(path); err == nil || errors.Is(err, ErrStaleIndex) {
+		t.Errorf("expected error for invalid offsets, got: %v", err)
+	}
+
+	// Huge number of lines
+	data := append([]byte(lineIndexMagic), 14, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01)
+	os.WriteFile(path+LineIndexSuffix, data, 0644)
+	if _, err := LoadLineIndex(path); err == nil {
+		t.Errorf("expected error for invalid number of lines")
+	}
+
+	// Offsets in middle of lines
+	index.Offsets = []int64{0, 2, 6}
+	index.Save()
+	if lines, err := ReadLines(path, 1, 2); err != nil || !reflect.DeepEqual(lines, FileLines{"two"}) {
+		t.Errorf("expected index to be rebuilt, got: %q, error: %v", lines, err)
+	}
+}
+
+// Test bounding of index cache
+func TestLineIndexCache(t *testing.T) {
+	tempDir := t.TempDir()
+	first := filepath.Join(tempDir, "first.log")
+	os.WriteFile(first, []byte("one\ntwo\n"), 0644)
+	if _, err := ReadLines(first, 0, 1); err != nil {
+		t.Fatalf("expected to read lines, error: %v", err)
+	}
+
+	for i := 0; i < lineIndexCacheSize; i++ {
+		path := filepath.Join(tempDir, fmt.Sprintf("%v.log", i))
+		os.WriteFile(path, []byte("line\n"), 0644)
+		GetLineIndex(path)
+	}
+
+	lineIndexCache.Lock()
+	_, ok := lineIndexCache.entries[first]
+	count := len(lineIndexCache.entries)
+	lineIndexCache.Unlock()
+	if ok || count > lineIndexCacheSize {
+		t.Errorf("expected oldest index to be evicted, got %v entries", count)
+	}
+}
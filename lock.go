@@ -0,0 +1,144 @@
+package fs_utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// ErrLocked is returned by TryLock functions
+// when the lock is held by someone else.
+var ErrLocked = errors.New("file is locked")
+
+const (
+	lockRetryMin = time.Millisecond
+	lockRetryMax = 100 * time.Millisecond
+)
+
+// FileLock is an advisory lock on a file.
+// Advisory locks only work between processes which take them,
+// they don't prevent others from reading or writing the file.
+// Should be initialized by LockPath, RLockPath, TryLockPath
+// or File methods Lock, RLock and TryLock.
+// Must be released by Unlock.
+type FileLock struct {
+	Path   string
+	Shared bool
+	file   *os.File
+}
+
+// LockPath takes exclusive lock on file at specific path.
+// Waits until the lock is free or ctx is done.
+// If ctx is done first, returns ctx.Err().
+func LockPath(ctx context.Context, path string) (*FileLock, error) {
+	return lockPath(ctx, path, false)
+}
+
+// RLockPath takes shared lock on file at specific path.
+// Many shared locks can be held at once, but not with exclusive one.
+// Waits until the lock is free or ctx is done.
+func RLockPath(ctx context.Context, path string) (*FileLock, error) {
+	return lockPath(ctx, path, true)
+}
+
+// TryLockPath takes exclusive lock on file at specific path without waiting.
+// If the lock is held, returns ErrLocked.
+func TryLockPath(path string) (*FileLock, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(file, false); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &FileLock{Path: path, file: file}, nil
+}
+
+// Lock takes exclusive lock on the file.
+// Works same as LockPath.
+func (f *File) Lock(ctx context.Context) (*FileLock, error) {
+	return LockPath(ctx, f.Path)
+}
+
+// RLock takes shared lock on the file.
+// Works same as RLockPath.
+func (f *File) RLock(ctx context.Context) (*FileLock, error) {
+	return RLockPath(ctx, f.Path)
+}
+
+// TryLock takes exclusive lock on the file without waiting.
+// Works same as TryLockPath.
+func (f *File) TryLock() (*FileLock, error) {
+	return TryLockPath(f.Path)
+}
+
+// Unlock releases the lock.
+func (l *FileLock) Unlock() error {
+	if l.file == nil {
+		return nil
+	}
+
+	err := unlockFile(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
+
+// lockPath opens file and takes the lock on it.
+func lockPath(ctx context.Context, path string, shared bool) (*FileLock, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := waitLock(ctx, file, shared); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &FileLock{Path: path, Shared: shared, file: file}, nil
+}
+
+// waitLock retries to take the lock on opened file until ctx is done.
+// Blocking lock calls can't be interrupted, so the lock is polled
+// with growing delay.
+func waitLock(ctx context.Context, file *os.File, shared bool) error {
+	delay := lockRetryMin
+	for {
+		err := lockFile(file, shared)
+		if !errors.Is(err, ErrLocked) {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, lockRetryMax)
+	}
+}
+
+// AppendToFileLocked works same as AppendToFile,
+// but holds exclusive lock on the file while content is written,
+// so appends of cooperating processes don't interleave.
+// Waits for the lock until ctx is done.
+func AppendToFileLocked(ctx context.Context, path string, content FileLines) error {
+	return appendContent(ctx, path, content, true)
+}
+
+// WriteContentLocked works same as WriteContent,
+// but holds exclusive lock on the file while content is replaced.
+// Waits for the lock until ctx is done.
+func WriteContentLocked(ctx context.Context, path string, content FileLines) error {
+	return writeContent(ctx, path, content, true)
+}
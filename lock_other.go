@@ -0,0 +1,19 @@
+//go:build !(darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd)
+
+package fs_utils
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile takes lock on opened file without waiting.
+// Advisory locks are only implemented on systems with flock.
+func lockFile(file *os.File, shared bool) error {
+	return errors.ErrUnsupported
+}
+
+// unlockFile releases lock on opened file.
+func unlockFile(file *os.File) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,126 @@
+//go:build darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd
+
+package fs_utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTryLockPath(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.txt")
+	os.WriteFile(path, []byte("test"), 0644)
+
+	// Test taking a free lock
+	lock, err := TryLockPath(path)
+	if err != nil {
+		t.Fatalf("expected to lock file: %v, error: %v", path, err)
+	}
+
+	// Test taking a held lock
+	f := &File{Path: path}
+	if _, err := f.TryLock(); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected locked error, got: %v", err)
+	}
+
+	// Test waiting for a held lock with timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := f.Lock(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error, got: %v", err)
+	}
+
+	// Test taking the lock after unlock
+	if err := lock.Unlock(); err != nil {
+		t.Errorf("expected to unlock file: %v, error: %v", path, err)
+	}
+	lock, err = f.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("expected to lock file: %v, error: %v", path, err)
+	}
+	lock.Unlock()
+}
+
+func TestRLockPath(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.txt")
+	os.WriteFile(path, []byte("test"), 0644)
+
+	// Test taking two shared locks
+	first, err := RLockPath(context.Background(), path)
+	if err != nil {
+		t.Fatalf("expected to lock file: %v, error: %v", path, err)
+	}
+	defer first.Unlock()
+
+	second, err := RLockPath(context.Background(), path)
+	if err != nil {
+		t.Fatalf("expected to lock file: %v, error: %v", path, err)
+	}
+	defer second.Unlock()
+
+	// Test taking exclusive lock while shared ones are held
+	if _, err := TryLockPath(path); !errors.Is(err, ErrLocked) {
+		t.Errorf("expected locked error, got: %v", err)
+	}
+}
+
+func TestAppendToFileLocked(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.txt")
+	os.WriteFile(path, nil, 0644)
+
+	// Test appending long lines from many goroutines
+	const writers = 8
+	line := strings.Repeat("x", 8192)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			content := FileLines{fmt.Sprintf("%v:%v", i, line), fmt.Sprintf("%v:%v", i, line)}
+			if err := AppendToFileLocked(context.Background(), path, content); err != nil {
+				t.Errorf("expected to append to file: %v, error: %v", path, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Verify lines of every writer are whole and adjacent
+	lines, err := GetFileContent(path)
+	if err != nil {
+		t.Fatalf("expected to read file: %v, error: %v", path, err)
+	}
+	if len(lines) != writers*2 {
+		t.Fatalf("expected %v lines, got: %v", writers*2, len(lines))
+	}
+	for i := 0; i < len(lines); i += 2 {
+		if lines[i] != lines[i+1] || !strings.HasSuffix(lines[i], line) {
+			t.Errorf("expected lines %v and %v to be written together", i+1, i+2)
+		}
+	}
+}
+
+func TestWriteContentLocked(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.txt")
+	os.WriteFile(path, []byte("old content which is longer\n"), 0644)
+
+	// Test replacing content
+	if err := WriteContentLocked(context.Background(), path, FileLines{"new"}); err != nil {
+		t.Fatalf("expected to write file: %v, error: %v", path, err)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "new\n" {
+		t.Errorf("expected content to be replaced, got: %q", content)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd
+
+package fs_utils
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes flock lock on opened file without waiting.
+// If the lock is held, returns ErrLocked.
+func lockFile(file *os.File, shared bool) error {
+	how := syscall.LOCK_EX | syscall.LOCK_NB
+	if shared {
+		how = syscall.LOCK_SH | syscall.LOCK_NB
+	}
+
+	for {
+		err := syscall.Flock(int(file.Fd()), how)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err == syscall.EWOULDBLOCK {
+			return ErrLocked
+		}
+		if err != nil {
+			return &os.PathError{Op: "flock", Path: file.Name(), Err: err}
+		}
+		return nil
+	}
+}
+
+// unlockFile releases flock lock on opened file.
+func unlockFile(file *os.File) error {
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_UN); err != nil {
+		return &os.PathError{Op: "flock", Path: file.Name(), Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package fs_utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockFileAttempts is how many times AcquireLockFile retries
+// when lock file is replaced while it's being taken.
+const lockFileAttempts = 10
+
+// LockInfo contains information about holder of a lock file.
+type LockInfo struct {
+	PID     int
+	Host    string
+	Started time.Time
+}
+
+// String returns holder in human-readable format.
+func (li LockInfo) String() string {
+	return fmt.Sprintf("pid %v on %v since %v", li.PID, li.Host, li.Started.Format(time.RFC3339))
+}
+
+// LockFile is a lock file which allows only one instance
+// of a program to run, for example per directory.
+// The file contains PID, host and start time of holder
+// and is guarded by advisory lock, so it's released by the system
+// when holder dies.
+// Should be initialized by AcquireLockFile and released by Close.
+type LockFile struct {
+	Path string
+	Info LockInfo
+	// Stale contains information of previous holder,
+	// if its lock was left by a dead process and was taken over.
+	Stale *LockInfo
+	file  *os.File
+}
+
+// LockHeldError is returned by AcquireLockFile
+// when the lock is held by other alive process.
+type LockHeldError struct {
+	Path string
+	// Holder is nil if lock file couldn't be read.
+	Holder *LockInfo
+}
+
+func (e *LockHeldError) Error() string {
+	if e.Holder == nil {
+		return fmt.Sprintf("lock %v is held by unknown process", e.Path)
+	}
+	return fmt.Sprintf("lock %v is held by %v", e.Path, e.Holder)
+}
+
+// Unwrap returns ErrLocked.
+func (e *LockHeldError) Unwrap() error {
+	return ErrLocked
+}
+
+// AcquireLockFile atomically creates lock file at specific path
+// and writes information about current process to it.
+// If lock file exists but isn't held, it was left by a dead process;
+// then it's taken over and previous holder is set to Stale.
+// If the lock is held, returns *LockHeldError.
+func AcquireLockFile(path string) (*LockFile, error) {
+	for attempt := 0; attempt < lockFileAttempts; attempt++ {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+		created := err == nil
+		if os.IsExist(err) {
+			file, err = os.OpenFile(path, os.O_RDWR, 0)
+			if os.IsNotExist(err) {
+				// Holder released the lock meanwhile.
+				continue
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := lockFile(file, false); err != nil {
+			_ = file.Close()
+			if errors.Is(err, ErrLocked) {
+				holder, _ := ReadLockFile(path)
+				return nil, &LockHeldError{Path: path, Holder: holder}
+			}
+			return nil, err
+		}
+
+		// Previous holder could remove the file after it was opened,
+		// then the lock was taken on a file nobody else sees.
+		if !isFileAtPath(file, path) {
+			_ = file.Close()
+			continue
+		}
+
+		l := &LockFile{
+			Path: path,
+			Info: LockInfo{PID: os.Getpid(), Started: time.Now().Truncate(time.Second)},
+			file: file,
+		}
+		l.Info.Host, _ = os.Hostname()
+
+		if !created {
+			l.Stale, _ = ReadLockFile(path)
+		}
+
+		if err := l.write(); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+
+		return l, nil
+	}
+
+	return nil, fmt.Errorf("couldn't acquire lock %v: lock file keeps changing", path)
+}
+
+// isFileAtPath reports whether opened file is still located at path.
+func isFileAtPath(file *os.File, path string) bool {
+	openedInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(openedInfo, pathInfo)
+}
+
+// write replaces content of lock file with l.Info.
+func (l *LockFile) write() error {
+	if err := l.file.Truncate(0); err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("%v\n%v\n%v\n", l.Info.PID, l.Info.Host, l.Info.Started.Format(time.RFC3339))
+	if _, err := l.file.WriteAt([]byte(content), 0); err != nil {
+		return err
+	}
+
+	return l.file.Sync()
+}
+
+// Close removes lock file and releases the lock.
+func (l *LockFile) Close() error {
+	if l.file == nil {
+		return nil
+	}
+
+	// File is removed while the lock is still held,
+	// so nobody takes the lock on the removed file.
+	err := os.Remove(l.Path)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
+
+// ReadLockFile reads information about holder of lock file.
+// If file doesn't exist or has invalid format, returns an error.
+func ReadLockFile(path string) (*LockInfo, error) {
+	lines, err := GetFileContent(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) < 3 {
+		return nil, fmt.Errorf("invalid lock file %v", path)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid lock file %v: %w", path, err)
+	}
+
+	started, err := time.Parse(time.RFC3339, strings.TrimSpace(lines[2]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid lock file %v: %w", path, err)
+	}
+
+	return &LockInfo{PID: pid, Host: strings.TrimSpace(lines[1]), Started: started}, nil
+}
@@ -0,0 +1,71 @@
+//go:build darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd
+
+package fs_utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquireLockFile(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "app.lock")
+
+	// Test acquiring a free lock
+	lock, err := AcquireLockFile(path)
+	if err != nil {
+		t.Fatalf("expected to acquire lock: %v, error: %v", path, err)
+	}
+	if lock.Stale != nil {
+		t.Errorf("expected no stale holder, got: %v", lock.Stale)
+	}
+
+	info, err := ReadLockFile(path)
+	if err != nil || info.PID != os.Getpid() {
+		t.Errorf("expected lock file to contain current pid, got: %v, error: %v", info, err)
+	}
+
+	// Test acquiring a held lock
+	_, err = AcquireLockFile(path)
+	var held *LockHeldError
+	if !errors.As(err, &held) || !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected lock held error, got: %v", err)
+	}
+	if held.Holder == nil || held.Holder.PID != os.Getpid() {
+		t.Errorf("expected holder to be current process, got: %v", held.Holder)
+	}
+
+	// Test releasing the lock
+	if err := lock.Close(); err != nil {
+		t.Errorf("expected to release lock: %v, error: %v", path, err)
+	}
+	if IsFileExists(path) {
+		t.Errorf("expected lock file to be removed: %v", path)
+	}
+}
+
+func TestAcquireLockFileStale(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "app.lock")
+
+	// Create lock file left by a dead process
+	os.WriteFile(path, []byte("999999\nother-host\n2024-01-02T03:04:05Z\n"), 0644)
+
+	// Test taking over the stale lock
+	lock, err := AcquireLockFile(path)
+	if err != nil {
+		t.Fatalf("expected to acquire stale lock: %v, error: %v", path, err)
+	}
+	defer lock.Close()
+
+	if lock.Stale == nil || lock.Stale.PID != 999999 || lock.Stale.Host != "other-host" {
+		t.Errorf("expected stale holder to be reported, got: %v", lock.Stale)
+	}
+
+	info, err := ReadLockFile(path)
+	if err != nil || info.PID != os.Getpid() {
+		t.Errorf("expected lock file to contain current pid, got: %v, error: %v", info, err)
+	}
+}
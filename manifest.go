@@ -0,0 +1,327 @@
+package fs_utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestEntry is a line of checksum manifest.
+// Path is slash-separated and relative to manifest directory.
+// Binary is set by "*" marker, it doesn't change checksum.
+type ManifestEntry struct {
+	Hash   string
+	Path   string
+	Binary bool
+}
+
+// Manifest is a list of checksums, same as sha256sum and similar tools write.
+type Manifest []ManifestEntry
+
+// ManifestMismatch is a file, whose checksum differs from manifest.
+type ManifestMismatch struct {
+	Path string
+	Want string
+	Got  string
+}
+
+// ManifestReport is a result of VerifyManifest.
+// Missing files are listed in manifest but don't exist,
+// Extra files exist but aren't listed in manifest.
+type ManifestReport struct {
+	Verified   int
+	Missing    []string
+	Extra      []string
+	Mismatched []ManifestMismatch
+}
+
+// OK reports whether all files match manifest.
+func (r ManifestReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Mismatched) == 0
+}
+
+// ManifestName returns conventional name of manifest file,
+// for example SHA256SUMS.
+func ManifestName(algo HashAlgorithm) string {
+	return strings.ToUpper(string(algo)) + "SUMS"
+}
+
+// manifestAlgorithms are algorithms detected by length of hex-encoded checksum.
+var manifestAlgorithms = map[int]HashAlgorithm{
+	8:   CRC32,
+	32:  MD5,
+	40:  SHA1,
+	64:  SHA256,
+	128: SHA512,
+}
+
+// BuildManifest returns checksums of all regular files under dir,
+// sorted by path. Files matching exclude patterns are skipped.
+func BuildManifest(dir string, algo HashAlgorithm, exclude ...string) (Manifest, error) {
+	files, err := filterFiles(dir, nil, exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	for _, path := range files {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := HashFile(path, algo)
+		if err != nil {
+			return nil, err
+		}
+		manifest = append(manifest, ManifestEntry{Hash: sum, Path: filepath.ToSlash(rel)})
+	}
+
+	sort.Slice(manifest, func(i, j int) bool {
+		return manifest[i].Path < manifest[j].Path
+	})
+	return manifest, nil
+}
+
+// WriteManifest writes checksums of all files under dir
+// to file named by ManifestName in dir. Manifest itself isn't listed.
+// Returns path of the manifest.
+func WriteManifest(dir string, algo HashAlgorithm) (string, error) {
+	path := filepath.Join(dir, ManifestName(algo))
+	all, err := BuildManifest(dir, algo)
+	if err != nil {
+		return "", err
+	}
+
+	// Exclude pattern would match manifests of subdirectories too.
+	var manifest Manifest
+	for _, entry := range all {
+		if entry.Path != ManifestName(algo) {
+			manifest = append(manifest, entry)
+		}
+	}
+
+	err = writeFile(path, DefaultPerm, func(w io.Writer) error {
+		return manifest.Fprint(w)
+	})
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Fprint writes manifest to w in format of sha256sum.
+// Names with backslashes or newlines are escaped, same as coreutils does.
+func (m Manifest) Fprint(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, entry := range m {
+		mode := " "
+		if entry.Binary {
+			mode = "*"
+		}
+
+		name, escaped := escapeManifestName(entry.Path)
+		prefix := ""
+		if escaped {
+			prefix = "\\"
+		}
+
+		if _, err := fmt.Fprintf(bw, "%v%v %v%v\n", prefix, entry.Hash, mode, name); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// escapeManifestName escapes backslashes and newlines in name.
+// Returns false, if name didn't need escaping.
+func escapeManifestName(name string) (string, bool) {
+	if !strings.ContainsAny(name, "\\\n\r") {
+		return name, false
+	}
+	replacer := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r")
+	return replacer.Replace(name), true
+}
+
+// unescapeManifestName reverts escapeManifestName.
+func unescapeManifestName(name string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '\\' {
+			sb.WriteByte(name[i])
+			continue
+		}
+
+		i++
+		if i == len(name) {
+			return "", fmt.Errorf("unfinished escape in %q", name)
+		}
+		switch name[i] {
+		case '\\':
+			sb.WriteByte('\\')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		default:
+			return "", fmt.Errorf("unknown escape \\%c in %q", name[i], name)
+		}
+	}
+	return sb.String(), nil
+}
+
+// ParseManifest reads manifest written by sha256sum, md5sum
+// or similar tools. Both text and binary ("*") modes are accepted.
+// Empty lines are skipped.
+func ParseManifest(r io.Reader) (Manifest, error) {
+	var manifest Manifest
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		entry, err := parseManifestLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %w", number, err)
+		}
+		manifest = append(manifest, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// parseManifestLine parses line like "<hash>  <name>" or "<hash> *<name>".
+func parseManifestLine(line string) (ManifestEntry, error) {
+	escaped := strings.HasPrefix(line, "\\")
+	if escaped {
+		line = line[1:]
+	}
+
+	hash, rest, ok := strings.Cut(line, " ")
+	if !ok || rest == "" || !isHex(hash) || manifestAlgorithms[len(hash)] == "" {
+		return ManifestEntry{}, fmt.Errorf("invalid checksum line %q", line)
+	}
+
+	entry := ManifestEntry{Hash: strings.ToLower(hash)}
+	switch rest[0] {
+	case '*':
+		entry.Binary = true
+		rest = rest[1:]
+	case ' ':
+		rest = rest[1:]
+	}
+	if rest == "" {
+		return ManifestEntry{}, fmt.Errorf("missing file name in %q", line)
+	}
+
+	entry.Path = rest
+	if escaped {
+		name, err := unescapeManifestName(rest)
+		if err != nil {
+			return ManifestEntry{}, err
+		}
+		entry.Path = name
+	}
+
+	return entry, nil
+}
+
+// isHex reports whether s consists of hexadecimal digits.
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadManifest reads manifest file.
+func ReadManifest(path string) (Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseManifest(file)
+}
+
+// VerifyManifest checks files under dir against manifest file at path.
+// Paths in manifest are relative to dir. Algorithm is detected
+// by length of checksums. Manifest file itself isn't reported as extra.
+// Returns error if manifest or files couldn't be read,
+// or manifest lists paths outside of dir.
+func VerifyManifest(dir, path string) (*ManifestReport, error) {
+	manifest, err := ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ManifestReport{}
+	listed := make(map[string]bool)
+	for _, entry := range manifest {
+		if !filepath.IsLocal(filepath.FromSlash(entry.Path)) {
+			return nil, fmt.Errorf("path %v in manifest is outside of %v", entry.Path, dir)
+		}
+		rel := filepath.ToSlash(filepath.Clean(filepath.FromSlash(entry.Path)))
+		listed[rel] = true
+
+		location := filepath.Join(dir, filepath.FromSlash(rel))
+		info, err := os.Stat(location)
+		if os.IsNotExist(err) {
+			report.Missing = append(report.Missing, entry.Path)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%v is a directory", location)
+		}
+
+		sum, err := HashFile(location, manifestAlgorithms[len(entry.Hash)])
+		if err != nil {
+			return nil, err
+		}
+		if sum != entry.Hash {
+			report.Mismatched = append(report.Mismatched, ManifestMismatch{entry.Path, entry.Hash, sum})
+			continue
+		}
+		report.Verified++
+	}
+
+	files, err := filterFiles(dir, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	manifestPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, location := range files {
+		if abs, err := filepath.Abs(location); err == nil && abs == manifestPath {
+			continue
+		}
+		rel, err := filepath.Rel(dir, location)
+		if err != nil {
+			return nil, err
+		}
+		if rel = filepath.ToSlash(rel); !listed[rel] {
+			report.Extra = append(report.Extra, rel)
+		}
+	}
+
+	return report, nil
+}
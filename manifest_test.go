@@ -0,0 +1,110 @@
+package fs_utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteManifest(t *testing.T) {
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "sub"), 0755)
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("hello\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "sub", "b.txt"), []byte("hello\n"), 0644)
+	// Manifest of subdirectory is listed as other files
+	os.WriteFile(filepath.Join(tempDir, "sub", "SHA256SUMS"), []byte("hello\n"), 0644)
+
+	path, err := WriteManifest(tempDir, SHA256)
+	if err != nil {
+		t.Fatalf("expected to write manifest, error: %v", err)
+	}
+	if filepath.Base(path) != "SHA256SUMS" {
+		t.Errorf("expected manifest SHA256SUMS, got: %v", path)
+	}
+
+	data, _ := os.ReadFile(path)
+	sum := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	expected := sum + "  a.txt\n" + sum + "  sub/SHA256SUMS\n" + sum + "  sub/b.txt\n"
+	if string(data) != expected {
+		t.Errorf("expected manifest:\n%v\ngot:\n%v", expected, string(data))
+	}
+
+	report, err := VerifyManifest(tempDir, path)
+	if err != nil {
+		t.Fatalf("expected to verify manifest, error: %v", err)
+	}
+	if !report.OK() || report.Verified != 3 {
+		t.Errorf("expected 3 verified files, got: %+v", report)
+	}
+
+	// Manifest is regenerated without listing itself.
+	if _, err := WriteManifest(tempDir, SHA256); err != nil {
+		t.Fatalf("expected to rewrite manifest, error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != expected {
+		t.Errorf("expected same manifest, got:\n%v", string(data))
+	}
+}
+
+func TestVerifyManifest(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("hello\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "b.txt"), []byte("changed\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "extra.txt"), []byte("extra\n"), 0644)
+
+	// Manifest in format of md5sum with binary marker.
+	sum := "b1946ac92492d2347c6235b4d2611184"
+	manifest := sum + " *a.txt\r\n" + sum + "  b.txt\n" + sum + "  missing.txt\n"
+	path := filepath.Join(tempDir, "MD5SUMS")
+	os.WriteFile(path, []byte(manifest), 0644)
+
+	report, err := VerifyManifest(tempDir, path)
+	if err != nil {
+		t.Fatalf("expected to verify manifest, error: %v", err)
+	}
+	if report.Verified != 1 || report.OK() {
+		t.Errorf("expected 1 verified file and failure, got: %+v", report)
+	}
+	if !reflect.DeepEqual(report.Missing, []string{"missing.txt"}) {
+		t.Errorf("expected missing.txt to be missing, got: %v", report.Missing)
+	}
+	if !reflect.DeepEqual(report.Extra, []string{"extra.txt"}) {
+		t.Errorf("expected extra.txt to be extra, got: %v", report.Extra)
+	}
+	if len(report.Mismatched) != 1 || report.Mismatched[0].Path != "b.txt" || report.Mismatched[0].Want != sum {
+		t.Errorf("expected b.txt to mismatch, got: %v", report.Mismatched)
+	}
+
+	// Test refusing paths outside of dir
+	for _, name := range []string{"../a.txt", "sub/../../a.txt", "/a.txt"} {
+		os.WriteFile(path, []byte(sum+"  "+name+"\n"), 0644)
+		if _, err := VerifyManifest(tempDir, path); err == nil {
+			t.Errorf("expected error for path %v", name)
+		}
+	}
+}
+
+func TestParseManifest(t *testing.T) {
+	sum := "b1946ac92492d2347c6235b4d2611184"
+	manifest, err := ParseManifest(strings.NewReader(sum + " *bin.dat\n\n\\" + sum + "  a\\\\b\\nc\n"))
+	if err != nil {
+		t.Fatalf("expected to parse manifest, error: %v", err)
+	}
+
+	expected := Manifest{{sum, "bin.dat", true}, {sum, "a\\b\nc", false}}
+	if !reflect.DeepEqual(manifest, expected) {
+		t.Errorf("expected manifest %v, got: %v", expected, manifest)
+	}
+
+	var sb strings.Builder
+	manifest.Fprint(&sb)
+	if sb.String() != sum+" *bin.dat\n\\"+sum+"  a\\\\b\\nc\n" {
+		t.Errorf("expected escaped manifest, got: %q", sb.String())
+	}
+
+	if _, err := ParseManifest(strings.NewReader("xyz  a.txt\n")); err == nil {
+		t.Errorf("expected error for invalid checksum")
+	}
+}
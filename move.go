@@ -0,0 +1,88 @@
+package fs_utils
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// moveNoReplace moves path to target, which mustn't exist.
+// Within one filesystem path is renamed by renameNoReplace,
+// across filesystems it's copied and then removed.
+func moveNoReplace(path, target string) error {
+	err := renameNoReplace(path, target)
+	if !isCrossDevice(err) {
+		return err
+	}
+	return moveAcross(path, target)
+}
+
+// checkedRename renames oldPath to newPath, if newPath doesn't exist.
+// Unlike renameNoReplace, check and rename aren't one atomic step,
+// so it's used only where atomic way isn't supported.
+func checkedRename(oldPath, newPath string) error {
+	if _, err := os.Lstat(newPath); err == nil {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: fs.ErrExist}
+	}
+	return os.Rename(oldPath, newPath)
+}
+
+// moveAcross copies path to target, then removes path.
+// Copy is made in temporary directory next to target
+// and renamed to target only when it's complete,
+// so target is never replaced or left half-copied.
+func moveAcross(path, target string) error {
+	temp, err := os.MkdirTemp(filepath.Dir(target), "."+filepath.Base(target)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(temp)
+
+	copied := filepath.Join(temp, filepath.Base(target))
+	if err := copyTree(path, copied); err != nil {
+		return err
+	}
+	if err := renameNoReplace(copied, target); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(path)
+}
+
+// copyTree copies file, symlink or directory with its content
+// from source to destination, keeping permissions and modification times.
+// Directories get their permissions after their content is copied,
+// so read-only directories can be copied too.
+func copyTree(source, destination string) error {
+	var dirs []string
+	var modes []os.FileMode
+	err := filepath.Walk(source, func(location string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, location)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, rel)
+
+		if info.IsDir() {
+			dirs = append(dirs, target)
+			modes = append(modes, info.Mode().Perm())
+			return os.Mkdir(target, 0700)
+		}
+
+		return syncEntry(location, info, target)
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i], modes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
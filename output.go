@@ -0,0 +1,173 @@
+package fs_utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LineFormat configures how numbered lines are written.
+// Line is written as number padded to Width, then Separator and text.
+// Zero value writes lines same as DefaultLineFormat.
+type LineFormat struct {
+	// Width is minimal width of line number, numbers are padded by spaces.
+	Width int
+	// Separator is written between number and text, ". " if empty.
+	Separator string
+	// Start is number of first line, 1 if 0.
+	Start int
+	// NoNumbers writes only text of lines.
+	NoNumbers bool
+	// JSON writes every line as JSON object {"line":1,"text":"..."}.
+	JSON bool
+}
+
+// DefaultLineFormat is format of OutputFileContent and FileLines.Output.
+var DefaultLineFormat = LineFormat{Separator: ". ", Start: 1}
+
+// jsonLine is a line written by LineFormat with JSON set.
+type jsonLine struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// lineWriter writes lines with numbers by format.
+type lineWriter struct {
+	w      *bufio.Writer
+	format LineFormat
+	number int
+}
+
+func newLineWriter(w io.Writer, format LineFormat) *lineWriter {
+	if format.Separator == "" {
+		format.Separator = DefaultLineFormat.Separator
+	}
+	if format.Start == 0 {
+		format.Start = DefaultLineFormat.Start
+	}
+	return &lineWriter{w: bufio.NewWriter(w), format: format, number: format.Start}
+}
+
+// write writes single line and increments line number.
+func (lw *lineWriter) write(text string) error {
+	defer func() {
+		lw.number++
+	}()
+
+	switch {
+	case lw.format.JSON:
+		data, err := json.Marshal(jsonLine{lw.number, text})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(lw.w, "%s\n", data)
+		return err
+	case lw.format.NoNumbers:
+		_, err := fmt.Fprintf(lw.w, "%v\n", text)
+		return err
+	}
+
+	_, err := fmt.Fprintf(lw.w, "%*d%v%v\n", lw.format.Width, lw.number, lw.format.Separator, text)
+	return err
+}
+
+// FprintFileContent writes content of file to w by format.
+// File is read line by line, so it isn't loaded to memory.
+// If file looks binary, returns *BinaryFileError.
+// If reading fails, lines read before are still written.
+func FprintFileContent(w io.Writer, path string, format LineFormat) error {
+	if err := checkText(path); err != nil {
+		return err
+	}
+	return fprintFile(w, path, format)
+}
+
+// fprintFile writes content of file to w by format,
+// without checking whether file is binary.
+func fprintFile(w io.Writer, path string, format LineFormat) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	lw := newLineWriter(w, format)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if err := lw.write(scanner.Text()); err != nil {
+			return err
+		}
+	}
+
+	flushErr := lw.w.Flush()
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+// Fprint writes lines to w by format.
+func (fl FileLines) Fprint(w io.Writer, format LineFormat) error {
+	lw := newLineWriter(w, format)
+	for _, line := range fl {
+		if err := lw.write(line); err != nil {
+			return err
+		}
+	}
+	return lw.w.Flush()
+}
+
+// Fprint writes directory to w, same as Output does.
+func (d Dir) Fprint(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Path: %v\nChildren: %v\n", d.Path, d.Children)
+	return err
+}
+
+// Fprint writes numbered actions to w, same as Output does.
+func (p Plan) Fprint(w io.Writer) error {
+	for i, a := range p {
+		if _, err := fmt.Fprintf(w, "%v. %v\n", i+1, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FprintDir walks directory and writes found entries to w,
+// same as ReadDirW does.
+func FprintDir(w io.Writer, path string) error {
+	return fprintDir(w, path, "dir")
+}
+
+// fprintDir walks directory and writes entries to w.
+// dirName is a word used for directories.
+func fprintDir(w io.Writer, path, dirName string) error {
+	return filepath.Walk(path, func(location string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			_, err = fmt.Fprintf(w, "found %v: %v\n", dirName, location)
+		} else {
+			_, err = fmt.Fprintf(w, "found file: %v\n", location)
+		}
+		return err
+	})
+}
+
+// FprintDirD works same as ReadDirD, but writes to w
+// and returns error instead of outputting it.
+// Returns ID of the operation.
+func FprintDirD(w io.Writer, path string) (string, error) {
+	id := generateID(16)
+	if _, err := fmt.Fprintf(w, "%v: starting scanning directory... (path: %v)\n", id, path); err != nil {
+		return id, err
+	}
+
+	return id, fprintDir(w, path, "directory")
+}
@@ -0,0 +1,87 @@
+package fs_utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFprintFileContent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.txt")
+	os.WriteFile(path, []byte("HI!\nBYE!\n"), 0644)
+
+	tests := []struct {
+		format   LineFormat
+		expected string
+	}{
+		{DefaultLineFormat, "1. HI!\n2. BYE!\n"},
+		{LineFormat{Width: 3, Separator: " | ", Start: 10}, " 10 | HI!\n 11 | BYE!\n"},
+		{LineFormat{NoNumbers: true}, "HI!\nBYE!\n"},
+		{LineFormat{JSON: true, Start: 1}, "{\"line\":1,\"text\":\"HI!\"}\n{\"line\":2,\"text\":\"BYE!\"}\n"},
+	}
+
+	for _, tt := range tests {
+		var buffer bytes.Buffer
+		if err := FprintFileContent(&buffer, path, tt.format); err != nil {
+			t.Errorf("expected to write file content, error: %v", err)
+		}
+		if buffer.String() != tt.expected {
+			t.Errorf("expected output: %q, got: %q", tt.expected, buffer.String())
+		}
+	}
+
+	// Test error instead of panic
+	if err := FprintFileContent(&bytes.Buffer{}, path+".missing", DefaultLineFormat); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+
+	// Test lines before too long line are written
+	os.WriteFile(path, []byte("HI!\n"+strings.Repeat("x", 100*1024)+"\n"), 0644)
+	var buffer bytes.Buffer
+	if err := FprintFileContent(&buffer, path, DefaultLineFormat); err == nil || buffer.String() != "1. HI!\n" {
+		t.Errorf("expected error after written lines, got: %q, error: %v", buffer.String(), err)
+	}
+}
+
+func TestFprintDir(t *testing.T) {
+	tempDir := t.TempDir()
+	file := filepath.Join(tempDir, "file.txt")
+	os.WriteFile(file, []byte("test"), 0644)
+
+	var buffer bytes.Buffer
+	if err := FprintDir(&buffer, tempDir); err != nil {
+		t.Errorf("expected to write directory: %v, error: %v", tempDir, err)
+	}
+	if !strings.Contains(buffer.String(), "found file: "+file+"\n") {
+		t.Errorf("expected file in output, got: %v", buffer.String())
+	}
+
+	buffer.Reset()
+	id, err := FprintDirD(&buffer, tempDir)
+	if err != nil || !strings.HasPrefix(buffer.String(), id+": starting scanning directory...") {
+		t.Errorf("expected output to start with ID, got: %v, error: %v", buffer.String(), err)
+	}
+}
+
+func TestFileLinesFprint(t *testing.T) {
+	var buffer bytes.Buffer
+	FileLines{"a", "b"}.Fprint(&buffer, LineFormat{Separator: ": "})
+	if buffer.String() != "1: a\n2: b\n" {
+		t.Errorf("expected lines numbered from 1, got: %q", buffer.String())
+	}
+
+	// Test zero value of format
+	buffer.Reset()
+	FileLines{"a", "b"}.Fprint(&buffer, LineFormat{})
+	if buffer.String() != "1. a\n2. b\n" {
+		t.Errorf("expected default format, got: %q", buffer.String())
+	}
+
+	buffer.Reset()
+	(Dir{Path: "test"}).Fprint(&buffer)
+	if buffer.String() != "Path: test\nChildren: []\n" {
+		t.Errorf("expected directory output, got: %q", buffer.String())
+	}
+}
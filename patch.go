@@ -0,0 +1,515 @@
+package fs_utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrPatchRejected is returned when some hunks of patch couldn't be applied.
+var ErrPatchRejected = errors.New("patch hunks rejected")
+
+// devNull is a path, which marks created and deleted files in patches.
+const devNull = "/dev/null"
+
+// hunkHeader matches header like "@@ -1,3 +1,4 @@".
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Hunk is a part of unified diff.
+// Every line starts with " " for context, "-" for deleted
+// or "+" for inserted line. Line starting with "\" marks,
+// that previous line has no newline at end of file.
+type Hunk struct {
+	OldStart int
+	OldCount int
+	NewStart int
+	NewCount int
+	Lines    []string
+}
+
+// FilePatch contains hunks of single file.
+// Created file has OldPath "/dev/null", deleted one has NewPath "/dev/null".
+type FilePatch struct {
+	OldPath string
+	NewPath string
+	Hunks   []Hunk
+}
+
+// IsNew reports whether patch creates file.
+func (p *FilePatch) IsNew() bool {
+	return p.OldPath == devNull
+}
+
+// IsDelete reports whether patch deletes file.
+func (p *FilePatch) IsDelete() bool {
+	return p.NewPath == devNull
+}
+
+// PatchOptions configures ApplyPatch.
+type PatchOptions struct {
+	// Strip removes leading path components from paths in patch,
+	// like patch -p does. Use 1 for patches made by git.
+	Strip int
+	// Fuzz is how many context lines at start and end of hunk
+	// can be ignored, when hunk doesn't match exactly.
+	Fuzz int
+	// AllOrNothing writes nothing, if any hunk is rejected.
+	AllOrNothing bool
+	// DryRun checks whether patch applies without changing files.
+	DryRun bool
+}
+
+// FilePatchResult describes how patch was applied to single file.
+type FilePatchResult struct {
+	Path     string
+	Applied  int
+	Rejected []Hunk
+}
+
+// PatchResult contains results for all files of patch.
+type PatchResult struct {
+	Files []FilePatchResult
+}
+
+// Rejected returns number of rejected hunks in all files.
+func (r *PatchResult) Rejected() int {
+	count := 0
+	for _, file := range r.Files {
+		count += len(file.Rejected)
+	}
+	return count
+}
+
+// String renders hunk like it appears in unified diff.
+func (h Hunk) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "@@ -%v,%v +%v,%v @@\n", h.OldStart, h.OldCount, h.NewStart, h.NewCount)
+	for _, line := range h.Lines {
+		builder.WriteString(line + "\n")
+	}
+	return builder.String()
+}
+
+// ParsePatch parses unified diff, which can contain multiple files.
+// Lines outside of file patches, like git headers, are skipped.
+func ParsePatch(text string) ([]FilePatch, error) {
+	lines := splitLines(text)
+
+	var patches []FilePatch
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "--- ") || i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "+++ ") {
+			continue
+		}
+
+		patch := FilePatch{OldPath: patchPath(lines[i]), NewPath: patchPath(lines[i+1])}
+		i += 2
+		for i < len(lines) && strings.HasPrefix(lines[i], "@@") {
+			hunk, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			patch.Hunks = append(patch.Hunks, hunk)
+			i = next
+		}
+		i--
+
+		patches = append(patches, patch)
+	}
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("patch doesn't contain any file")
+	}
+
+	return patches, nil
+}
+
+// patchPath returns path from "---" or "+++" line without timestamp.
+func patchPath(line string) string {
+	path := line[4:]
+	if i := strings.IndexByte(path, '\t'); i >= 0 {
+		path = path[:i]
+	}
+	return strings.TrimSpace(path)
+}
+
+// parseHunk parses hunk starting at line i.
+// Returns index of line after the hunk.
+func parseHunk(lines []string, i int) (Hunk, int, error) {
+	match := hunkHeader.FindStringSubmatch(lines[i])
+	if match == nil {
+		return Hunk{}, 0, fmt.Errorf("invalid hunk header at line %v: %v", i+1, lines[i])
+	}
+
+	number := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	hunk := Hunk{OldStart: number(match[1]), OldCount: number(match[2]), NewStart: number(match[3]), NewCount: number(match[4])}
+
+	oldLeft, newLeft := hunk.OldCount, hunk.NewCount
+	for i++; i < len(lines) && (oldLeft > 0 || newLeft > 0); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, `\`):
+		case line == "":
+			// Some tools strip trailing space of empty context lines.
+			line = " "
+			oldLeft--
+			newLeft--
+		case line[0] == ' ':
+			oldLeft--
+			newLeft--
+		case line[0] == '-':
+			oldLeft--
+		case line[0] == '+':
+			newLeft--
+		default:
+			return Hunk{}, 0, fmt.Errorf("unexpected line %v in hunk: %v", i+1, line)
+		}
+		hunk.Lines = append(hunk.Lines, line)
+	}
+
+	if oldLeft != 0 || newLeft != 0 {
+		return Hunk{}, 0, fmt.Errorf("hunk at line %v is truncated", i+1)
+	}
+
+	// Keep "No newline at end of file" marker after last line.
+	for i < len(lines) && strings.HasPrefix(lines[i], `\`) {
+		hunk.Lines = append(hunk.Lines, lines[i])
+		i++
+	}
+
+	return hunk, i, nil
+}
+
+// sides returns lines which hunk expects and lines of hunk,
+// without leading and trailing context lines dropped by fuzz.
+func (h Hunk) sides(fuzz int) (FileLines, []string, int) {
+	leading := 0
+	for leading < len(h.Lines) && leading < fuzz && h.Lines[leading][0] == ' ' {
+		leading++
+	}
+	trailing := 0
+	for trailing < len(h.Lines)-leading && trailing < fuzz && h.Lines[len(h.Lines)-trailing-1][0] == ' ' {
+		trailing++
+	}
+
+	lines := h.Lines[leading : len(h.Lines)-trailing]
+	var old FileLines
+	for _, line := range lines {
+		if line[0] == ' ' || line[0] == '-' {
+			old = append(old, line[1:])
+		}
+	}
+	return old, lines, leading
+}
+
+// hunkResult returns lines, which replace matched lines of file.
+// Context lines are kept from matched, so their endings don't change.
+// Inserted lines end with eol, unless they are marked
+// with "No newline at end of file".
+func hunkResult(lines []string, matched FileLines, eol string) FileLines {
+	var result FileLines
+	k := 0
+	for i, line := range lines {
+		switch line[0] {
+		case ' ':
+			result = append(result, matched[k])
+			k++
+		case '-':
+			k++
+		case '+':
+			if i+1 < len(lines) && lines[i+1][0] == '\\' {
+				result = append(result, line[1:])
+			} else {
+				result = append(result, line[1:]+eol)
+			}
+		}
+	}
+	return result
+}
+
+// ApplyHunks applies hunks to lines in order.
+// Hunk, which doesn't match at its position, is searched
+// before and after it. Then up to fuzz context lines
+// at its start and end are ignored.
+// Returns changed lines and hunks which couldn't be applied.
+func ApplyHunks(lines FileLines, hunks []Hunk, fuzz int) (FileLines, []Hunk) {
+	return applyHunks(lines, hunks, fuzz, "")
+}
+
+// applyHunks works same as ApplyHunks,
+// but lines can end with line endings, which are kept.
+// Inserted lines end with eol.
+func applyHunks(lines FileLines, hunks []Hunk, fuzz int, eol string) (FileLines, []Hunk) {
+	result := append(FileLines(nil), lines...)
+
+	var rejected []Hunk
+	// offset is difference between positions in result and in original lines.
+	offset, minPos := 0, 0
+	for _, hunk := range hunks {
+		applied := false
+		for f := 0; f <= fuzz && !applied; f++ {
+			old, hunkLines, leading := hunk.sides(f)
+			start := hunk.OldStart - 1 + leading + offset
+			if hunk.OldCount == 0 {
+				// Empty old range points to line before insertion.
+				start++
+			}
+
+			pos, ok := findHunk(result, old, start, minPos)
+			if !ok {
+				continue
+			}
+
+			new := hunkResult(hunkLines, result[pos:pos+len(old)], eol)
+			result = append(result[:pos], append(new, result[pos+len(old):]...)...)
+			offset += pos - start + len(new) - len(old)
+			minPos = pos + len(new)
+			applied = true
+		}
+
+		if !applied {
+			rejected = append(rejected, hunk)
+		}
+	}
+
+	return result, rejected
+}
+
+// findHunk searches old lines in lines, starting at position start
+// and going further from it in both directions.
+// Lines before minPos aren't searched.
+func findHunk(lines, old FileLines, start, minPos int) (int, bool) {
+	start = max(minPos, min(start, len(lines)-len(old)))
+
+	for distance := 0; ; distance++ {
+		before, after := start-distance, start+distance
+		if before < minPos && after > len(lines)-len(old) {
+			return 0, false
+		}
+		if after <= len(lines)-len(old) && linesMatch(lines[after:], old) {
+			return after, true
+		}
+		if distance > 0 && before >= minPos && linesMatch(lines[before:], old) {
+			return before, true
+		}
+	}
+}
+
+// linesMatch reports whether lines start with expected.
+// Line endings of lines are ignored.
+func linesMatch(lines, expected FileLines) bool {
+	if len(lines) < len(expected) {
+		return false
+	}
+	for i, line := range expected {
+		if trimLineEnding(lines[i]) != line {
+			return false
+		}
+	}
+	return true
+}
+
+// trimLineEnding removes "\n" or "\r\n" from end of line.
+func trimLineEnding(line string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
+
+// splitLinesEndings splits text to lines, which keep their endings.
+// Last line doesn't end with newline, if text doesn't.
+func splitLinesEndings(text string) FileLines {
+	var lines FileLines
+	for len(text) > 0 {
+		i := strings.IndexByte(text, '\n') + 1
+		if i == 0 {
+			i = len(text)
+		}
+		lines = append(lines, text[:i])
+		text = text[i:]
+	}
+	return lines
+}
+
+// lineEnding returns ending used by lines, "\r\n" or "\n".
+func lineEnding(lines FileLines) string {
+	if len(lines) > 0 && strings.HasSuffix(lines[0], "\r\n") {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// stripPath removes n leading components from path.
+// Stripped path must stay inside of directory,
+// so absolute paths and paths with ".." are refused.
+func stripPath(path string, n int) (string, error) {
+	if path == devNull {
+		return path, nil
+	}
+
+	parts := strings.Split(path, "/")
+	if n >= len(parts) {
+		return "", fmt.Errorf("can't strip %v components from %v", n, path)
+	}
+
+	stripped := filepath.FromSlash(strings.Join(parts[n:], "/"))
+	if !filepath.IsLocal(stripped) {
+		return "", fmt.Errorf("path %v in patch is outside of root", path)
+	}
+	return stripped, nil
+}
+
+// checkSymlinks refuses name, if any of its components under root
+// is a symlink. filepath.IsLocal checks path only lexically,
+// so symlink could point outside of root.
+func checkSymlinks(root, name string) error {
+	path := root
+	for _, part := range strings.Split(name, string(filepath.Separator)) {
+		path = filepath.Join(path, part)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("path %v in patch goes through symlink %v", name, path)
+		}
+	}
+	return nil
+}
+
+// patchedFile is a file, which is changed by patch.
+// Lines of content keep their endings.
+type patchedFile struct {
+	path    string
+	content FileLines
+	remove  bool
+}
+
+// ApplyPatch applies unified diff to files under root.
+// Patch can create and delete files.
+// If some hunks are rejected, returns error ErrPatchRejected,
+// other hunks are still applied unless opts.AllOrNothing is set.
+// Changed files are replaced atomically.
+// Paths going through symlinks and several patches
+// of the same file are refused.
+func ApplyPatch(root, patch string, opts PatchOptions) (*PatchResult, error) {
+	patches, err := ParsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PatchResult{}
+	var changes []patchedFile
+	seen := make(map[string]bool)
+	for _, filePatch := range patches {
+		change, fileResult, err := applyFilePatch(root, filePatch, opts)
+		if err != nil {
+			return nil, err
+		}
+		// Second write would drop changes of the first one.
+		if seen[fileResult.Path] {
+			return nil, fmt.Errorf("file %v is patched more than once", fileResult.Path)
+		}
+		seen[fileResult.Path] = true
+		result.Files = append(result.Files, fileResult)
+		if fileResult.Applied > 0 {
+			changes = append(changes, change)
+		}
+	}
+
+	rejected := result.Rejected()
+	if (rejected > 0 && opts.AllOrNothing) || opts.DryRun {
+		return result, rejectedError(rejected)
+	}
+
+	for _, change := range changes {
+		if err := writePatchedFile(change); err != nil {
+			return result, err
+		}
+	}
+
+	return result, rejectedError(rejected)
+}
+
+// rejectedError returns ErrPatchRejected with count, if count isn't 0.
+func rejectedError(count int) error {
+	if count == 0 {
+		return nil
+	}
+	return fmt.Errorf("%v: %w", count, ErrPatchRejected)
+}
+
+// applyFilePatch applies hunks of single file in memory.
+func applyFilePatch(root string, filePatch FilePatch, opts PatchOptions) (patchedFile, FilePatchResult, error) {
+	name := filePatch.NewPath
+	if filePatch.IsDelete() {
+		name = filePatch.OldPath
+	}
+	name, err := stripPath(name, opts.Strip)
+	if err != nil {
+		return patchedFile{}, FilePatchResult{}, err
+	}
+	if err := checkSymlinks(root, name); err != nil {
+		return patchedFile{}, FilePatchResult{}, err
+	}
+
+	path := filepath.Join(root, name)
+	fileResult := FilePatchResult{Path: path}
+
+	var lines FileLines
+	if filePatch.IsNew() {
+		if IsFileExists(path) {
+			fileResult.Rejected = filePatch.Hunks
+			return patchedFile{}, fileResult, nil
+		}
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return patchedFile{}, FilePatchResult{}, err
+		}
+		lines = splitLinesEndings(string(data))
+	}
+
+	content, rejected := applyHunks(lines, filePatch.Hunks, opts.Fuzz, lineEnding(lines))
+	fileResult.Rejected = rejected
+	fileResult.Applied = len(filePatch.Hunks) - len(rejected)
+
+	change := patchedFile{path: path, content: content}
+	if filePatch.IsDelete() {
+		if len(rejected) > 0 || len(content) > 0 {
+			// Content differs from deleted file, so it's kept.
+			fileResult.Rejected = filePatch.Hunks
+			fileResult.Applied = 0
+		}
+		change.remove = true
+	}
+
+	return change, fileResult, nil
+}
+
+// writePatchedFile writes or removes patched file.
+func writePatchedFile(change patchedFile) error {
+	if change.remove {
+		return os.Remove(change.path)
+	}
+
+	if _, err := mkdirAllPerm(filepath.Dir(change.path), DefaultPerm); err != nil {
+		return err
+	}
+
+	return writeFile(change.path, DefaultPerm, func(w io.Writer) error {
+		_, err := io.WriteString(w, strings.Join(change.content, ""))
+		return err
+	})
+}
@@ -0,0 +1,231 @@
+package fs_utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPatch = `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,3 +1,3 @@
+ one
+-two
++TWO
+ three
+diff --git a/new.txt b/new.txt
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++new
++file
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-old
+`
+
+func TestParsePatch(t *testing.T) {
+	patches, err := ParsePatch(testPatch)
+	if err != nil {
+		t.Fatalf("expected to parse patch, error: %v", err)
+	}
+	if len(patches) != 3 {
+		t.Fatalf("expected 3 file patches, got: %v", len(patches))
+	}
+	if !patches[1].IsNew() || !patches[2].IsDelete() {
+		t.Errorf("expected created and deleted files, got: %v", patches)
+	}
+	if hunk := patches[0].Hunks[0]; hunk.OldCount != 3 || len(hunk.Lines) != 4 {
+		t.Errorf("expected hunk with 4 lines, got: %v", hunk)
+	}
+}
+
+func TestApplyHunksOffsetFuzz(t *testing.T) {
+	lines := FileLines{"x", "x", "a", "b", "c", "d", "e"}
+	hunks := []Hunk{{OldStart: 1, OldCount: 3, NewStart: 1, NewCount: 3, Lines: []string{" a", "-b", "+B", " C"}}}
+
+	// Test rejection without fuzz
+	if _, rejected := ApplyHunks(lines, hunks, 0); len(rejected) != 1 {
+		t.Errorf("expected hunk to be rejected without fuzz")
+	}
+
+	// Test offset and fuzz
+	result, rejected := ApplyHunks(lines, hunks, 1)
+	if len(rejected) != 0 {
+		t.Fatalf("expected hunk to be applied with fuzz")
+	}
+	if !reflect.DeepEqual(result, FileLines{"x", "x", "a", "B", "c", "d", "e"}) {
+		t.Errorf("expected hunk to be applied at offset, got: %v", result)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("one\ntwo\nthree\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "old.txt"), []byte("old\n"), 0644)
+
+	result, err := ApplyPatch(tempDir, testPatch, PatchOptions{Strip: 1})
+	if err != nil {
+		t.Fatalf("expected to apply patch, error: %v", err)
+	}
+	if len(result.Files) != 3 || result.Rejected() != 0 {
+		t.Errorf("expected 3 patched files, got: %v", result.Files)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(tempDir, "a.txt"))
+	if string(content) != "one\nTWO\nthree\n" {
+		t.Errorf("expected file to be patched, got: %q", content)
+	}
+	content, _ = os.ReadFile(filepath.Join(tempDir, "new.txt"))
+	if string(content) != "new\nfile\n" {
+		t.Errorf("expected file to be created, got: %q", content)
+	}
+	if IsFileExists(filepath.Join(tempDir, "old.txt")) {
+		t.Errorf("expected file to be deleted")
+	}
+}
+
+func TestApplyPatchAllOrNothing(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("one\ntwo\nthree\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "old.txt"), []byte("changed\n"), 0644)
+
+	result, err := ApplyPatch(tempDir, testPatch, PatchOptions{Strip: 1, AllOrNothing: true})
+	if !errors.Is(err, ErrPatchRejected) {
+		t.Fatalf("expected ErrPatchRejected, got: %v", err)
+	}
+	if result.Rejected() != 1 {
+		t.Errorf("expected 1 rejected hunk, got: %v", result.Rejected())
+	}
+
+	content, _ := os.ReadFile(filepath.Join(tempDir, "a.txt"))
+	if string(content) != "one\ntwo\nthree\n" || IsFileExists(filepath.Join(tempDir, "new.txt")) {
+		t.Errorf("expected nothing to be written")
+	}
+}
+
+// Test refusing paths outside of root
+func TestApplyPatchOutsideRoot(t *testing.T) {
+	tempDir := t.TempDir()
+	root := filepath.Join(tempDir, "root")
+	os.Mkdir(root, os.ModePerm)
+
+	for _, path := range []string{"b/../escaped.txt", "/" + filepath.ToSlash(tempDir) + "/escaped.txt"} {
+		patch := "--- /dev/null\n+++ " + path + "\n@@ -0,0 +1 @@\n+escaped\n"
+		strip := 1
+		if strings.HasPrefix(path, "/") {
+			strip = 0
+		}
+		if _, err := ApplyPatch(root, patch, PatchOptions{Strip: strip}); err == nil {
+			t.Errorf("expected error for path %v", path)
+		}
+	}
+	if IsFileExists(filepath.Join(tempDir, "escaped.txt")) {
+		t.Errorf("expected file outside of root not to be created")
+	}
+}
+
+// Test refusing paths through symlinks inside root
+func TestApplyPatchSymlink(t *testing.T) {
+	tempDir := t.TempDir()
+	root := filepath.Join(tempDir, "root")
+	os.Mkdir(root, os.ModePerm)
+	os.WriteFile(filepath.Join(tempDir, "secret.txt"), []byte("secret\n"), 0644)
+	if err := os.Symlink(tempDir, filepath.Join(root, "link")); err != nil {
+		t.Skipf("symlinks aren't supported: %v", err)
+	}
+	os.Symlink(filepath.Join(tempDir, "secret.txt"), filepath.Join(root, "secret.txt"))
+
+	patches := []string{
+		"--- /dev/null\n+++ b/link/escaped.txt\n@@ -0,0 +1 @@\n+escaped\n",
+		"--- a/secret.txt\n+++ b/secret.txt\n@@ -1 +1 @@\n-secret\n+changed\n",
+	}
+	for _, patch := range patches {
+		if _, err := ApplyPatch(root, patch, PatchOptions{Strip: 1}); err == nil {
+			t.Errorf("expected error for patch through symlink: %q", patch)
+		}
+	}
+	if IsFileExists(filepath.Join(tempDir, "escaped.txt")) {
+		t.Errorf("expected file outside of root not to be created")
+	}
+	if content, _ := os.ReadFile(filepath.Join(tempDir, "secret.txt")); string(content) != "secret\n" {
+		t.Errorf("expected file outside of root to be kept, got: %q", content)
+	}
+}
+
+// Test refusing several patches of the same file
+func TestApplyPatchDuplicate(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("one\ntwo\n"), 0644)
+
+	patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-one\n+ONE\n" +
+		"--- a/a.txt\n+++ b/a.txt\n@@ -2 +2 @@\n-two\n+TWO\n"
+	if _, err := ApplyPatch(tempDir, patch, PatchOptions{Strip: 1}); err == nil {
+		t.Errorf("expected error for duplicate file")
+	}
+	if content, _ := os.ReadFile(filepath.Join(tempDir, "a.txt")); string(content) != "one\ntwo\n" {
+		t.Errorf("expected nothing to be written, got: %q", content)
+	}
+}
+
+// Test keeping line endings and long lines
+func TestApplyPatchLineEndings(t *testing.T) {
+	tempDir := t.TempDir()
+	long := strings.Repeat("x", 100*1024)
+	os.WriteFile(filepath.Join(tempDir, "crlf.txt"), []byte("one\r\ntwo\r\n"+long+"\r\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "eof.txt"), []byte("one\ntwo"), 0644)
+
+	patch := `--- a/crlf.txt
++++ b/crlf.txt
+@@ -1,2 +1,2 @@
+-one
++ONE
+ two
+--- a/eof.txt
++++ b/eof.txt
+@@ -1,2 +1,2 @@
+-one
++ONE
+ two
+\ No newline at end of file
+`
+	if _, err := ApplyPatch(tempDir, patch, PatchOptions{Strip: 1}); err != nil {
+		t.Fatalf("expected to apply patch, error: %v", err)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(tempDir, "crlf.txt"))
+	if string(content) != "ONE\r\ntwo\r\n"+long+"\r\n" {
+		t.Errorf("expected CRLF endings and long line to be kept, got %v bytes", len(content))
+	}
+	content, _ = os.ReadFile(filepath.Join(tempDir, "eof.txt"))
+	if string(content) != "ONE\ntwo" {
+		t.Errorf("expected missing newline to be kept, got: %q", content)
+	}
+}
+
+// Test adding newline to last line
+func TestApplyPatchAddNewline(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "eof.txt"), []byte("one\ntwo"), 0644)
+
+	patch := `--- a/eof.txt
++++ b/eof.txt
+@@ -1,2 +1,3 @@
+ one
+-two
+\ No newline at end of file
++two
++three
+`
+	if _, err := ApplyPatch(tempDir, patch, PatchOptions{Strip: 1}); err != nil {
+		t.Fatalf("expected to apply patch, error: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(tempDir, "eof.txt")); string(content) != "one\ntwo\nthree\n" {
+		t.Errorf("expected newline to be added, got: %q", content)
+	}
+}
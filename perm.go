@@ -0,0 +1,220 @@
+package fs_utils
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// Perm contains modes for created files and directories.
+type Perm struct {
+	File os.FileMode
+	Dir  os.FileMode
+	// IgnoreUmask sets modes exactly, without filtering by process umask.
+	IgnoreUmask bool
+}
+
+// DefaultPerm is used by CreateDir*, CreateFile* and CopyFile.
+// Modes are filtered by process umask, same as os.Create and os.Mkdir do.
+// Can be replaced by caller, for example with PrivatePerm.
+// OpenRoot copies it to Root.Perm.
+var DefaultPerm = Perm{File: 0666, Dir: os.ModePerm}
+
+// PrivatePerm makes created files and directories
+// accessible only by owner, regardless of umask.
+var PrivatePerm = Perm{File: 0600, Dir: 0700, IgnoreUmask: true}
+
+// PermError is returned when created file or directory
+// got other mode than it was requested.
+// The file or directory is removed then.
+type PermError struct {
+	Path string
+	Want os.FileMode
+	Got  os.FileMode
+}
+
+func (e *PermError) Error() string {
+	return fmt.Sprintf("mode of %v is %v, expected %v", e.Path, e.Got, e.Want)
+}
+
+// verifyMode checks mode of created file or directory.
+// If umask is ignored, mode should be exactly as requested,
+// otherwise it shouldn't have bits which weren't requested.
+// Returns applied mode.
+func verifyMode(path string, info os.FileInfo, want os.FileMode, ignoreUmask bool) (os.FileMode, error) {
+	got := info.Mode().Perm()
+
+	// Windows only keeps read-only attribute.
+	if runtime.GOOS == "windows" {
+		return got, nil
+	}
+
+	if (ignoreUmask && got != want.Perm()) || got&^want.Perm() != 0 {
+		return got, &PermError{Path: path, Want: want.Perm(), Got: got}
+	}
+
+	return got, nil
+}
+
+// applyModeFD sets mode of opened file or directory if umask is ignored,
+// then verifies it.
+func applyModeFD(file *os.File, want os.FileMode, ignoreUmask bool) (os.FileMode, error) {
+	if ignoreUmask {
+		if err := file.Chmod(want); err != nil {
+			return 0, err
+		}
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	return verifyMode(file.Name(), info, want, ignoreUmask)
+}
+
+// mkdirPerm creates directory with mode from perm and verifies it.
+func mkdirPerm(path string, perm Perm) (os.FileMode, error) {
+	if err := os.Mkdir(path, perm.Dir); err != nil {
+		return 0, err
+	}
+
+	mode, err := applyDirPerm(path, perm)
+	if err != nil {
+		_ = os.Remove(path)
+		return mode, err
+	}
+
+	return mode, nil
+}
+
+// mkdirAllPerm creates directory with all missing parents.
+// Every created directory gets mode from perm and is verified.
+// Returns mode of path.
+func mkdirAllPerm(path string, perm Perm) (os.FileMode, error) {
+	missing, err := PlanCreateDirQ(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(path, perm.Dir); err != nil {
+		return 0, err
+	}
+
+	for _, action := range missing {
+		if _, err := applyDirPerm(action.Path, perm); err != nil {
+			return 0, err
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Mode().Perm(), nil
+}
+
+// applyDirPerm sets mode of created directory if umask is ignored,
+// then verifies it.
+func applyDirPerm(path string, perm Perm) (os.FileMode, error) {
+	if perm.IgnoreUmask {
+		if err := os.Chmod(path, perm.Dir); err != nil {
+			return 0, err
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return verifyMode(path, info, perm.Dir, perm.IgnoreUmask)
+}
+
+// CreateDirPerm works same as CreateDir,
+// but creates directory with mode from perm.
+// Returns mode which was actually applied.
+// If mode differs from requested, directory is removed
+// and *PermError is returned.
+func CreateDirPerm(path string, perm Perm) (os.FileMode, error) {
+	return mkdirPerm(path, perm)
+}
+
+// CreateDirQPerm works same as CreateDirQ,
+// but creates directories with mode from perm.
+// Returns mode of path.
+// If mode of created directory differs from requested,
+// then returns *PermError.
+func CreateDirQPerm(path string, perm Perm) (os.FileMode, error) {
+	return mkdirAllPerm(path, perm)
+}
+
+// CreateDirWPerm works same as CreateDirW,
+// but creates directory with mode from perm.
+// Returns mode which was actually applied.
+func CreateDirWPerm(path string, perm Perm) (*Dir, os.FileMode, error) {
+	mode, err := mkdirPerm(path, perm)
+	if err != nil {
+		return nil, mode, err
+	}
+	return &Dir{Path: path}, mode, nil
+}
+
+// CreateFileQPerm works same as CreateFileQ,
+// but creates file with mode from perm.
+// Returns mode which was actually applied.
+func CreateFileQPerm(path string, perm Perm) (*File, os.FileMode, error) {
+	mode, err := CreateFileRPerm(path, perm)
+	if err != nil {
+		return nil, mode, err
+	}
+	return newFile(path, []string{""}), mode, nil
+}
+
+// CreateFileRPerm works same as CreateFileR,
+// but creates file with mode from perm.
+// Returns mode which was actually applied.
+func CreateFileRPerm(path string, perm Perm) (os.FileMode, error) {
+	file, mode, err := createExclusive(path, perm)
+	if err != nil {
+		return mode, err
+	}
+	return mode, file.Close()
+}
+
+// CreateFileAPerm works same as CreateFileA,
+// but creates file with mode from perm.
+// Returns mode which was actually applied.
+func CreateFileAPerm(path string, content FileLines, perm Perm) (os.FileMode, error) {
+	_, mode, err := CreateFileWPerm(path, content, perm)
+	return mode, err
+}
+
+// CreateFileWPerm works same as CreateFileW,
+// but creates file with mode from perm.
+// Mode is verified before content is written,
+// so content never gets to file with wrong mode.
+// Returns mode which was actually applied.
+func CreateFileWPerm(path string, content FileLines, perm Perm) (*File, os.FileMode, error) {
+	file, mode, err := createExclusive(path, perm)
+	if err != nil {
+		return nil, mode, err
+	}
+
+	err = writeLines(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, mode, err
+	}
+
+	return newFile(path, content), mode, nil
+}
+
+// CopyFilePerm works same as CopyFile,
+// but creates destination with mode from perm.
+// Returns mode which was actually applied.
+func CopyFilePerm(source, destination string, perm Perm) (os.FileMode, error) {
+	return copyFile(source, destination, perm)
+}
@@ -0,0 +1,111 @@
+//go:build unix
+
+package fs_utils
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestCreatePrivatePerm(t *testing.T) {
+	tempDir := t.TempDir()
+	dir := filepath.Join(tempDir, "a", "b")
+	file := filepath.Join(dir, "secret.txt")
+
+	// Test creating directories
+	mode, err := CreateDirQPerm(dir, PrivatePerm)
+	if err != nil {
+		t.Fatalf("expected to create directory: %v, error: %v", dir, err)
+	}
+	if mode != 0700 {
+		t.Errorf("expected directory mode to be 0700, got: %v", mode)
+	}
+	if info, _ := os.Stat(filepath.Dir(dir)); info.Mode().Perm() != 0700 {
+		t.Errorf("expected parent directory mode to be 0700, got: %v", info.Mode())
+	}
+
+	// Test creating file
+	_, mode, err = CreateFileWPerm(file, FileLines{"secret"}, PrivatePerm)
+	if err != nil {
+		t.Fatalf("expected to create file: %v, error: %v", file, err)
+	}
+	if info, _ := os.Stat(file); mode != 0600 || info.Mode().Perm() != 0600 {
+		t.Errorf("expected file mode to be 0600, got: %v", mode)
+	}
+
+	// Test copying file
+	copied := filepath.Join(dir, "copy.txt")
+	if mode, err := CopyFilePerm(file, copied, PrivatePerm); err != nil || mode != 0600 {
+		t.Errorf("expected to copy file with mode 0600, got: %v, error: %v", mode, err)
+	}
+}
+
+// Test changes umask of whole process,
+// so it's run in a child process, where it can't affect other tests.
+func TestCreateDirPermUmask(t *testing.T) {
+	if os.Getenv("FS_UTILS_UMASK_TEST") != "1" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestCreateDirPermUmask$", "-test.count=1")
+		cmd.Env = append(os.Environ(), "FS_UTILS_UMASK_TEST=1")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Errorf("expected umask test to pass, error: %v, output:\n%s", err, output)
+		}
+		return
+	}
+
+	syscall.Umask(0077)
+
+	tempDir := t.TempDir()
+	perm := Perm{File: 0644, Dir: 0755}
+
+	// Test umask is applied by default
+	mode, err := CreateDirPerm(filepath.Join(tempDir, "masked"), perm)
+	if err != nil || mode != 0700 {
+		t.Errorf("expected umask to be applied, got: %v, error: %v", mode, err)
+	}
+
+	// Test ignoring umask
+	perm.IgnoreUmask = true
+	mode, err = CreateDirPerm(filepath.Join(tempDir, "exact"), perm)
+	if err != nil || mode != 0755 {
+		t.Errorf("expected mode 0755, got: %v, error: %v", mode, err)
+	}
+
+	// Test default permissions
+	file := filepath.Join(tempDir, "default.txt")
+	if err := CreateFileR(file); err != nil {
+		t.Fatalf("expected to create file: %v, error: %v", file, err)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0600 {
+		t.Errorf("expected file mode to be 0600, got: %v", info.Mode())
+	}
+
+	// Test other creating functions
+	if mode, err := CreateFileAPerm(filepath.Join(tempDir, "a.txt"), FileLines{"a"}, perm); err != nil || mode != 0644 {
+		t.Errorf("expected file mode 0644, got: %v, error: %v", mode, err)
+	}
+	perm.IgnoreUmask = false
+	if _, mode, err := CreateDirWPerm(filepath.Join(tempDir, "w"), perm); err != nil || mode != 0700 {
+		t.Errorf("expected umask to be applied, got: %v, error: %v", mode, err)
+	}
+}
+
+func TestVerifyMode(t *testing.T) {
+	tempDir := t.TempDir()
+	os.Chmod(tempDir, 0755)
+	info, _ := os.Stat(tempDir)
+
+	var permErr *PermError
+	if _, err := verifyMode(tempDir, info, 0700, false); !errors.As(err, &permErr) {
+		t.Errorf("expected PermError for extra bits, got: %v", err)
+	}
+	if _, err := verifyMode(tempDir, info, 0775, true); !errors.As(err, &permErr) {
+		t.Errorf("expected PermError for exact mode, got: %v", err)
+	}
+	if _, err := verifyMode(tempDir, info, 0777, false); err != nil {
+		t.Errorf("expected masked mode to be accepted, error: %v", err)
+	}
+}
@@ -0,0 +1,342 @@
+package fs_utils
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// PlanOp is a kind of filesystem action.
+type PlanOp string
+
+const (
+	PlanRemove PlanOp = "remove"
+	PlanMkdir  PlanOp = "mkdir"
+	PlanRename PlanOp = "rename"
+	PlanCopy   PlanOp = "copy"
+	PlanWrite  PlanOp = "write"
+	PlanChmod  PlanOp = "chmod"
+	PlanChown  PlanOp = "chown"
+)
+
+// PlanAction is a single filesystem action.
+// Target is set only for rename and copy actions.
+// Bytes is amount of data the action affects.
+// Mode is set only for chmod actions, UID and GID only for chown ones.
+type PlanAction struct {
+	Op     PlanOp
+	Path   string
+	Target string
+	Bytes  int64
+	Mode   os.FileMode
+	UID    int
+	GID    int
+}
+
+// Plan is list of actions which function would take.
+// Should be initialized by Plan* functions,
+// which never touch disk.
+type Plan []PlanAction
+
+// String returns action in human-readable format.
+func (a PlanAction) String() string {
+	switch a.Op {
+	case PlanChmod:
+		return fmt.Sprintf("%v %v (%v)", a.Op, a.Path, a.Mode)
+	case PlanChown:
+		return fmt.Sprintf("%v %v (%v:%v)", a.Op, a.Path, a.UID, a.GID)
+	}
+
+	if a.Target != "" {
+		return fmt.Sprintf("%v %v -> %v (%v bytes)", a.Op, a.Path, a.Target, a.Bytes)
+	}
+	return fmt.Sprintf("%v %v (%v bytes)", a.Op, a.Path, a.Bytes)
+}
+
+// TotalBytes returns sum of bytes of all actions.
+func (p Plan) TotalBytes() int64 {
+	var total int64
+	for _, a := range p {
+		total += a.Bytes
+	}
+	return total
+}
+
+// Output outputs plan with fmt.Printf.
+// If there aren't actions, outputs it.
+// Use Fprint to write it to other io.Writer.
+func (p Plan) Output() {
+	if len(p) == 0 {
+		fmt.Printf("Plan.Output: there aren't actions\n")
+		return
+	}
+
+	_ = p.Fprint(os.Stdout)
+}
+
+// PlanRemoveDir returns actions which RemoveDirQ would take.
+// Children are listed before their parents.
+// If directory doesn't exist or DefaultRemoveGuard refuses removal,
+// then returns an error.
+func PlanRemoveDir(path string) (Plan, error) {
+	if err := CheckRemove(path, DefaultRemoveGuard); err != nil {
+		return nil, err
+	}
+
+	if !IsDirExists(path) {
+		return nil, fmt.Errorf("dir doesn't exists: (%v)", path)
+	}
+
+	var plan Plan
+	var dirs []string
+	err := filepath.Walk(path, func(location string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			dirs = append(dirs, location)
+		} else {
+			plan = append(plan, PlanAction{Op: PlanRemove, Path: location, Bytes: info.Size()})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		plan = append(plan, PlanAction{Op: PlanRemove, Path: dirs[i]})
+	}
+
+	return plan, nil
+}
+
+// PlanRemoveDirW returns action which RemoveDirW would take.
+// If directory doesn't exist or isn't empty, then returns an error.
+func PlanRemoveDirW(d *Dir) (Plan, error) {
+	if !IsDirExists(d.Path) {
+		return nil, fmt.Errorf("dir %v doesn't exist", d.Path)
+	}
+
+	entries, err := os.ReadDir(d.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("dir %v isn't empty", d.Path)
+	}
+
+	return Plan{{Op: PlanRemove, Path: d.Path}}, nil
+}
+
+// PlanRemoveDirA returns action which RemoveDirA would take.
+// If directory doesn't exist or isn't empty, then returns an error.
+func PlanRemoveDirA(d *Dir) (Plan, error) {
+	return PlanRemoveDirW(d)
+}
+
+// PlanRemoveFile returns action which RemoveFileQ would take.
+// If it couldn't find the file, then returns an error.
+func PlanRemoveFile(path string) (Plan, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("file %v does not exist", path)
+	}
+
+	return Plan{{Op: PlanRemove, Path: path, Bytes: info.Size()}}, nil
+}
+
+// PlanRemoveFileW returns action which RemoveFileW would take.
+// If it couldn't find the file, then returns an error.
+func PlanRemoveFileW(f *File) (Plan, error) {
+	return PlanRemoveFile(f.Path)
+}
+
+// PlanRemoveFileA returns action which RemoveFileA would take.
+// If it couldn't find the file, then returns an error.
+func PlanRemoveFileA(f *File) (Plan, error) {
+	return PlanRemoveFile(f.Path)
+}
+
+// PlanRemoveEmptyDir returns action which RemoveEmptyDir would take.
+// If directory doesn't exist or isn't empty, then returns an error.
+func PlanRemoveEmptyDir(path string) (Plan, error) {
+	return PlanRemoveDirW(&Dir{Path: path})
+}
+
+// PlanMoveDir returns action which MoveDir would take.
+// If the destination directory already exists, returns an error.
+func PlanMoveDir(sourcePath, destinationPath string) (Plan, error) {
+	if IsDirExists(destinationPath) {
+		return nil, fmt.Errorf("directory %v already exists", destinationPath)
+	}
+
+	size, err := treeSize(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return Plan{{Op: PlanRename, Path: sourcePath, Target: destinationPath, Bytes: size}}, nil
+}
+
+// PlanRenameFile returns action which RenameFile would take.
+// If the newPath already exists, returns an error.
+func PlanRenameFile(oldPath, newPath string) (Plan, error) {
+	if IsFileExists(newPath) {
+		return nil, fmt.Errorf("file %v already exists", newPath)
+	}
+
+	info, err := os.Stat(oldPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return Plan{{Op: PlanRename, Path: oldPath, Target: newPath, Bytes: info.Size()}}, nil
+}
+
+// PlanCopyFile returns action which CopyFile would take.
+// If the destination file already exists, returns an error.
+func PlanCopyFile(source, destination string) (Plan, error) {
+	if IsFileExists(destination) {
+		return nil, fmt.Errorf("file %v already exists", destination)
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return Plan{{Op: PlanCopy, Path: source, Target: destination, Bytes: info.Size()}}, nil
+}
+
+// PlanCreateDir returns action which CreateDir would take.
+// If directory already exists or its parent doesn't exist,
+// then returns an error.
+func PlanCreateDir(path string) (Plan, error) {
+	if IsDirExists(path) {
+		return nil, fmt.Errorf("dir %v already exists", path)
+	}
+	parent := filepath.Dir(filepath.Clean(path))
+	if info, err := os.Stat(parent); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("dir %v doesn't exist", parent)
+	}
+
+	return Plan{{Op: PlanMkdir, Path: path}}, nil
+}
+
+// PlanCreateDirW returns action which CreateDirW would take.
+// If directory already exists or its parent doesn't exist,
+// then returns an error.
+func PlanCreateDirW(path string) (Plan, error) {
+	return PlanCreateDir(path)
+}
+
+// PlanCreateDirQ returns actions which CreateDirQ would take.
+// Every missing directory of path is a separate action.
+// If path or one of its parents is a file, then returns an error.
+func PlanCreateDirQ(path string) (Plan, error) {
+	var missing []string
+	current := filepath.Clean(path)
+	for !IsDirExists(current) {
+		missing = append(missing, current)
+		parent := filepath.Dir(current)
+		if parent == current {
+			break
+		}
+		current = parent
+	}
+	if info, err := os.Stat(current); err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("%v isn't a directory", current)
+	}
+
+	var plan Plan
+	for i := len(missing) - 1; i >= 0; i-- {
+		plan = append(plan, PlanAction{Op: PlanMkdir, Path: missing[i]})
+	}
+
+	return plan, nil
+}
+
+// PlanCreateFileQ returns action which CreateFileQ would take.
+// If the file already exists, then returns an error.
+func PlanCreateFileQ(path string) (Plan, error) {
+	return PlanCreateFileW(path, nil)
+}
+
+// PlanCreateFileR returns action which CreateFileR would take.
+// If the file already exists, then returns an error.
+func PlanCreateFileR(path string) (Plan, error) {
+	return PlanCreateFileW(path, nil)
+}
+
+// PlanCreateFileA returns action which CreateFileA would take.
+// If the file already exists, then returns an error.
+func PlanCreateFileA(path string, content FileLines) (Plan, error) {
+	return PlanCreateFileW(path, content)
+}
+
+// PlanCreateFileW returns action which CreateFileW would take.
+// If the file already exists, then returns an error.
+func PlanCreateFileW(path string, content FileLines) (Plan, error) {
+	if IsFileExists(path) {
+		return nil, fmt.Errorf("file %v already exists", path)
+	}
+
+	return Plan{{Op: PlanWrite, Path: path, Bytes: contentSize(content)}}, nil
+}
+
+// PlanWriteContent returns action which WriteContent would take.
+// If the file doesn't exist, returns an error.
+func PlanWriteContent(path string, content FileLines) (Plan, error) {
+	if !IsFileExists(path) {
+		return nil, fmt.Errorf("file %v does not exist", path)
+	}
+
+	return Plan{{Op: PlanWrite, Path: path, Bytes: contentSize(content)}}, nil
+}
+
+// PlanAppendToFile returns action which AppendToFile would take.
+// If the file doesn't exist, returns an error.
+func PlanAppendToFile(path string, content FileLines) (Plan, error) {
+	if !IsFileExists(path) {
+		return nil, fmt.Errorf("file %v does not exist", path)
+	}
+
+	return Plan{{Op: PlanWrite, Path: path, Bytes: contentSize(content)}}, nil
+}
+
+// contentSize returns amount of bytes content takes in file.
+// Every line ends with a line break.
+func contentSize(content FileLines) int64 {
+	var size int64
+	for _, line := range content {
+		size += int64(len(line)) + 1
+	}
+	return size
+}
+
+// treeSize returns total size of files under path.
+func treeSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(location string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
@@ -0,0 +1,163 @@
+package fs_utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanRemoveDir(t *testing.T) {
+	tempDir := t.TempDir()
+	file1 := filepath.Join(tempDir, "file1.txt")
+	subdir := filepath.Join(tempDir, "subdir")
+	file2 := filepath.Join(subdir, "file2.txt")
+
+	// Create test files and subdirectory
+	os.Mkdir(subdir, os.ModePerm)
+	os.WriteFile(file1, []byte("test"), 0644)
+	os.WriteFile(file2, []byte("tested"), 0644)
+
+	// Test planning removal
+	plan, err := PlanRemoveDir(tempDir)
+	if err != nil {
+		t.Fatalf("expected to plan removal: %v, error: %v", tempDir, err)
+	}
+
+	if len(plan) != 4 {
+		t.Errorf("expected 4 actions, got: %v", plan)
+	}
+	if plan.TotalBytes() != 10 {
+		t.Errorf("expected 10 bytes, got: %v", plan.TotalBytes())
+	}
+	if last := plan[len(plan)-1]; last.Op != PlanRemove || last.Path != tempDir {
+		t.Errorf("expected root to be removed last, got: %v", last)
+	}
+
+	// Verify nothing was removed
+	if !IsFileExists(file2) {
+		t.Errorf("expected file to exist: %v", file2)
+	}
+}
+
+func TestPlanMoveDir(t *testing.T) {
+	tempDir := t.TempDir()
+	sourceDir := filepath.Join(tempDir, "source")
+	destinationDir := filepath.Join(tempDir, "destination")
+
+	os.Mkdir(sourceDir, os.ModePerm)
+	os.WriteFile(filepath.Join(sourceDir, "test.txt"), []byte("test"), 0644)
+
+	// Test planning move
+	plan, err := PlanMoveDir(sourceDir, destinationDir)
+	if err != nil {
+		t.Fatalf("expected to plan move: %v, error: %v", sourceDir, err)
+	}
+
+	expected := PlanAction{Op: PlanRename, Path: sourceDir, Target: destinationDir, Bytes: 4}
+	if len(plan) != 1 || plan[0] != expected {
+		t.Errorf("expected plan: %v, got: %v", expected, plan)
+	}
+
+	// Verify source directory wasn't moved
+	if !IsDirExists(sourceDir) || IsDirExists(destinationDir) {
+		t.Errorf("expected source directory to stay: %v", sourceDir)
+	}
+}
+
+func TestPlanCopyFileDestinationExists(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "source.txt")
+	destination := filepath.Join(tempDir, "destination.txt")
+
+	os.WriteFile(source, []byte("test"), 0644)
+	os.WriteFile(destination, []byte("test"), 0644)
+
+	// Test planning copy with existing destination
+	_, err := PlanCopyFile(source, destination)
+	if err == nil {
+		t.Errorf("expected error when copying to existing destination: %v", destination)
+	}
+}
+
+func TestPlanCreateDirQ(t *testing.T) {
+	tempDir := t.TempDir()
+	newDir := filepath.Join(tempDir, "newdir", "subdir")
+
+	// Test planning creation
+	plan, err := PlanCreateDirQ(newDir)
+	if err != nil {
+		t.Fatalf("expected to plan creation: %v, error: %v", newDir, err)
+	}
+
+	if len(plan) != 2 || plan[0].Path != filepath.Join(tempDir, "newdir") || plan[1].Path != newDir {
+		t.Errorf("expected parents to be created first, got: %v", plan)
+	}
+
+	// Verify directory wasn't created
+	if IsDirExists(newDir) {
+		t.Errorf("expected directory to not exist: %v", newDir)
+	}
+	// Test file in place of directory
+	file := filepath.Join(tempDir, "file")
+	os.WriteFile(file, []byte("test"), 0644)
+	for _, path := range []string{file, filepath.Join(file, "subdir")} {
+		if _, err := PlanCreateDirQ(path); err == nil {
+			t.Errorf("expected error for file in path: %v", path)
+		}
+	}
+}
+
+func TestPlanCreateDir(t *testing.T) {
+	tempDir := t.TempDir()
+	newDir := filepath.Join(tempDir, "newdir")
+
+	// Test planning creation
+	plan, err := PlanCreateDir(newDir)
+	if err != nil {
+		t.Fatalf("expected to plan creation: %v, error: %v", newDir, err)
+	}
+	if len(plan) != 1 || plan[0].Op != PlanMkdir || plan[0].Path != newDir {
+		t.Errorf("expected single mkdir action, got: %v", plan)
+	}
+
+	// Test missing parent
+	if _, err := PlanCreateDir(filepath.Join(newDir, "subdir")); err == nil {
+		t.Errorf("expected error for missing parent")
+	}
+}
+
+func TestPlanRemoveDirW(t *testing.T) {
+	tempDir := t.TempDir()
+	emptyDir := filepath.Join(tempDir, "empty")
+	os.Mkdir(emptyDir, os.ModePerm)
+
+	// Test planning removal of empty directory
+	plan, err := PlanRemoveDirW(&Dir{Path: emptyDir})
+	if err != nil {
+		t.Fatalf("expected to plan removal: %v, error: %v", emptyDir, err)
+	}
+	if len(plan) != 1 || plan[0].Path != emptyDir {
+		t.Errorf("expected single remove action, got: %v", plan)
+	}
+
+	// Test non-empty directory, which os.Remove would refuse
+	if _, err := PlanRemoveDirA(&Dir{Path: tempDir}); err == nil {
+		t.Errorf("expected error for non-empty directory")
+	}
+}
+
+func TestPlanWriteContent(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.txt")
+	os.WriteFile(path, nil, 0644)
+
+	// Test planning write
+	plan, err := PlanWriteContent(path, FileLines{"HI!", "BYE!"})
+	if err != nil {
+		t.Fatalf("expected to plan write: %v, error: %v", path, err)
+	}
+
+	if plan.TotalBytes() != 9 {
+		t.Errorf("expected 9 bytes, got: %v", plan.TotalBytes())
+	}
+}
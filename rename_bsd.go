@@ -0,0 +1,9 @@
+//go:build unix && !linux
+
+package fs_utils
+
+// renameNoReplace renames oldPath to newPath,
+// but never replaces existing newPath.
+func renameNoReplace(oldPath, newPath string) error {
+	return linkNoReplace(oldPath, newPath)
+}
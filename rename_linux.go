@@ -0,0 +1,77 @@
+package fs_utils
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	_AT_FDCWD         = -0x64
+	_RENAME_NOREPLACE = 0x1
+)
+
+// renameat2Trap is number of renameat2 syscall on current architecture.
+// It's 0 where number isn't known, then renameat2 isn't used.
+var renameat2Trap = map[string]uintptr{
+	"386":      353,
+	"amd64":    316,
+	"arm":      382,
+	"arm64":    276,
+	"loong64":  276,
+	"mips":     4351,
+	"mipsle":   4351,
+	"mips64":   5311,
+	"mips64le": 5311,
+	"ppc64":    357,
+	"ppc64le":  357,
+	"riscv64":  276,
+	"s390x":    347,
+}[runtime.GOARCH]
+
+// renameat2 renames file relative to directory descriptors with flags.
+func renameat2(olddirfd int, oldName string, newdirfd int, newName string, flags int) error {
+	if renameat2Trap == 0 {
+		return syscall.ENOSYS
+	}
+
+	oldPath, err := syscall.BytePtrFromString(oldName)
+	if err != nil {
+		return err
+	}
+	newPath, err := syscall.BytePtrFromString(newName)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall6(renameat2Trap, uintptr(olddirfd), uintptr(unsafe.Pointer(oldPath)),
+		uintptr(newdirfd), uintptr(unsafe.Pointer(newPath)), uintptr(flags), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// noReplaceUnsupported reports whether renameat2 failed,
+// because kernel or filesystem doesn't support RENAME_NOREPLACE.
+func noReplaceUnsupported(err error) bool {
+	return errors.Is(err, syscall.ENOSYS) || errors.Is(err, syscall.EINVAL) ||
+		errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.ENOTSUP)
+}
+
+// renameNoReplace renames oldPath to newPath,
+// but never replaces existing newPath.
+// Uses renameat2 with RENAME_NOREPLACE. Where it isn't supported,
+// falls back to linkNoReplace.
+func renameNoReplace(oldPath, newPath string) error {
+	err := renameat2(_AT_FDCWD, oldPath, _AT_FDCWD, newPath, _RENAME_NOREPLACE)
+	if noReplaceUnsupported(err) {
+		return linkNoReplace(oldPath, newPath)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build !unix
+
+package fs_utils
+
+import "os"
+
+// renameNoReplace renames oldPath to newPath,
+// but never replaces existing newPath.
+// Files are hardlinked and then unlinked. Directories are renamed directly,
+// because rename doesn't replace directories here.
+// Where hardlinks aren't supported, files are renamed
+// after checking that newPath doesn't exist.
+func renameNoReplace(oldPath, newPath string) error {
+	info, err := os.Lstat(oldPath)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return checkedRename(oldPath, newPath)
+	}
+
+	if err := os.Link(oldPath, newPath); err != nil {
+		if _, statErr := os.Lstat(newPath); statErr == nil {
+			return err
+		}
+		return checkedRename(oldPath, newPath)
+	}
+	return os.Remove(oldPath)
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+package fs_utils
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// linkNoReplace renames oldPath to newPath,
+// but never replaces existing newPath.
+// Files are hardlinked and then unlinked. Directories are renamed
+// over empty placeholder, which rename refuses to replace once it's filled.
+// On filesystems without hardlinks, like vfat, files are renamed
+// after checking that newPath doesn't exist.
+func linkNoReplace(oldPath, newPath string) error {
+	info, err := os.Lstat(oldPath)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		err := os.Link(oldPath, newPath)
+		if errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EOPNOTSUPP) {
+			return checkedRename(oldPath, newPath)
+		}
+		if err != nil {
+			return err
+		}
+		return os.Remove(oldPath)
+	}
+
+	if err := os.Mkdir(newPath, 0700); err != nil {
+		return err
+	}
+	// os.Rename refuses existing directories, so rename is called directly.
+	if err := syscall.Rename(oldPath, newPath); err != nil {
+		_ = os.Remove(newPath)
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package fs_utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// diffContext is number of unchanged lines around changes in previews.
+const diffContext = 3
+
+// ReplaceOptions configures Replace.
+type ReplaceOptions struct {
+	IgnoreCase bool
+	// FixedString makes pattern and replacement plain strings.
+	// Otherwise replacement can refer to capture groups as $1 or ${name}.
+	FixedString bool
+	// Include and Exclude filter files like in SearchOptions.
+	Include []string
+	Exclude []string
+	// DryRun makes previews without changing files.
+	DryRun bool
+	// Backup keeps original content of changed file in file with .bak suffix.
+	// Files with .bak suffix aren't changed then.
+	// Existing backups are never replaced, such files are reported by error.
+	Backup bool
+}
+
+// FileChange describes replacements in single file.
+// Diff is a unified diff of the change.
+type FileChange struct {
+	Path  string
+	Count int
+	Diff  string
+}
+
+// ReplaceResult contains changed files.
+type ReplaceResult struct {
+	Changes []FileChange
+}
+
+// Total returns number of replacements in all files.
+func (r *ReplaceResult) Total() int {
+	total := 0
+	for _, change := range r.Changes {
+		total += change.Count
+	}
+	return total
+}
+
+// Preview returns diffs of all changed files.
+func (r *ReplaceResult) Preview() string {
+	var builder strings.Builder
+	for _, change := range r.Changes {
+		builder.WriteString(change.Diff)
+	}
+	return builder.String()
+}
+
+// Replace replaces matches of pattern in every line of files under root.
+// Binary files are skipped. Line endings are kept.
+// Changed files are replaced atomically and keep their mode.
+// Files which couldn't be changed are reported by error,
+// other files are still changed.
+func Replace(root, pattern, replacement string, opts ReplaceOptions) (*ReplaceResult, error) {
+	re, err := compilePattern(pattern, opts.FixedString, opts.IgnoreCase)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := opts.Exclude
+	if opts.Backup {
+		exclude = append(exclude[:len(exclude):len(exclude)], "*.bak")
+	}
+
+	files, walkErr := filterFiles(root, opts.Include, exclude)
+
+	result := &ReplaceResult{}
+	errs := []error{walkErr}
+	for _, path := range files {
+		change, err := replaceInFile(path, re, replacement, opts)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if change != nil {
+			result.Changes = append(result.Changes, *change)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// replaceInFile replaces matches in single file.
+// Returns nil, if nothing was replaced.
+func replaceInFile(path string, re *regexp.Regexp, replacement string, opts ReplaceOptions) (*FileChange, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if DetectContentKind(data).IsBinary() {
+		return nil, nil
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	oldLines := make(FileLines, 0, len(lines))
+	newLines := make(FileLines, 0, len(lines))
+	count := 0
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		body := strings.TrimRight(line, "\r\n")
+		ending := line[len(body):]
+		n := len(re.FindAllStringIndex(body, -1))
+		oldLines = append(oldLines, body)
+		if n == 0 {
+			newLines = append(newLines, body)
+			continue
+		}
+
+		if opts.FixedString {
+			body = re.ReplaceAllLiteralString(body, replacement)
+		} else {
+			body = re.ReplaceAllString(body, replacement)
+		}
+		// Replacement can contain line breaks.
+		newLines = append(newLines, splitLines(body+"\n")...)
+		lines[i] = body + ending
+		count += n
+	}
+
+	if count == 0 {
+		return nil, nil
+	}
+
+	backup := path + ".bak"
+	if opts.Backup {
+		if _, err := os.Lstat(backup); err == nil {
+			return nil, fmt.Errorf("backup %v already exists", backup)
+		}
+	}
+
+	change := &FileChange{Path: path, Count: count, Diff: Diff(oldLines, newLines, DiffOptions{}).Unified(path, path, diffContext)}
+	if opts.DryRun {
+		return change, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Backup {
+		if err := writeBackup(backup, info.Mode().Perm(), data); err != nil {
+			return nil, err
+		}
+	}
+
+	err = writeAtomic(path, info.Mode().Perm(), func(w io.Writer) error {
+		_, err := io.WriteString(w, strings.Join(lines, ""))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// writeBackup writes data to new backup file with mode.
+// Existing file is never replaced.
+func writeBackup(path string, mode os.FileMode, data []byte) error {
+	file, _, err := createExclusive(path, Perm{File: mode, IgnoreUmask: true})
+	if err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return err
+}
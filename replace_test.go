@@ -0,0 +1,91 @@
+package fs_utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplace(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "config.ini")
+	other := filepath.Join(tempDir, "other.txt")
+	os.WriteFile(path, []byte("host=old.example.com\r\nport=80\r\nbackup=old.example.com"), 0640)
+	os.WriteFile(other, []byte("nothing here\n"), 0644)
+
+	opts := ReplaceOptions{DryRun: true, Backup: true}
+
+	// Test dry run
+	result, err := Replace(tempDir, `old\.(\w+)`, "new.$1", opts)
+	if err != nil {
+		t.Fatalf("expected to replace in: %v, error: %v", tempDir, err)
+	}
+	if len(result.Changes) != 1 || result.Total() != 2 {
+		t.Fatalf("expected 2 replacements in 1 file, got: %v", result.Changes)
+	}
+	if !strings.Contains(result.Preview(), "-host=old.example.com\n+host=new.example.com\n") {
+		t.Errorf("expected diff preview, got:\n%v", result.Preview())
+	}
+	content, _ := os.ReadFile(path)
+	if !strings.Contains(string(content), "old.example.com") {
+		t.Errorf("expected dry run to leave file untouched")
+	}
+
+	// Test replacing with backup
+	opts.DryRun = false
+	if _, err := Replace(tempDir, `old\.(\w+)`, "new.$1", opts); err != nil {
+		t.Fatalf("expected to replace in: %v, error: %v", tempDir, err)
+	}
+	content, _ = os.ReadFile(path)
+	if string(content) != "host=new.example.com\r\nport=80\r\nbackup=new.example.com" {
+		t.Errorf("expected content to be replaced with line endings kept, got: %q", content)
+	}
+	if backup, _ := os.ReadFile(path + ".bak"); !strings.Contains(string(backup), "old.example.com") {
+		t.Errorf("expected backup to keep original content, got: %q", backup)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("expected mode to be kept, got: %v", info.Mode())
+	}
+	if IsFileExists(other + ".bak") {
+		t.Errorf("expected unchanged file not to be backed up")
+	}
+}
+
+func TestReplaceExistingBackup(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "a.txt")
+	os.WriteFile(path, []byte("old\n"), 0644)
+	os.WriteFile(path+".bak", []byte("previous backup\n"), 0644)
+
+	// Test refusing to replace existing backup
+	if _, err := Replace(tempDir, "old", "new", ReplaceOptions{Backup: true}); err == nil {
+		t.Errorf("expected error for existing backup")
+	}
+	if content, _ := os.ReadFile(path); string(content) != "old\n" {
+		t.Errorf("expected file to be unchanged, got: %q", content)
+	}
+	if content, _ := os.ReadFile(path + ".bak"); string(content) != "previous backup\n" {
+		t.Errorf("expected backup to be kept, got: %q", content)
+	}
+}
+
+func TestReplaceMultiline(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "a.txt")
+	os.WriteFile(path, []byte("a, b\nc\n"), 0644)
+
+	result, err := Replace(tempDir, ", ", "\n", ReplaceOptions{FixedString: true})
+	if err != nil {
+		t.Fatalf("expected to replace in: %v, error: %v", tempDir, err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "a\nb\nc\n" {
+		t.Errorf("expected line to be split, got: %q", content)
+	}
+
+	// Preview should show split lines, like diff of the files does
+	expected := Diff(FileLines{"a, b", "c"}, FileLines{"a", "b", "c"}, DiffOptions{}).Unified(path, path, diffContext)
+	if result.Preview() != expected {
+		t.Errorf("expected preview:\n%v\ngot:\n%v", expected, result.Preview())
+	}
+}
@@ -0,0 +1,367 @@
+package fs_utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrPathEscapes is returned by Root methods
+// when a path resolves outside of the root.
+var ErrPathEscapes = errors.New("path escapes from root")
+
+// Root is a directory handle which confines all operations
+// to the directory it was opened on.
+// Paths passed to its methods are relative to the root.
+// Paths which resolve outside the root, including through
+// symlinks, are rejected with ErrPathEscapes.
+// Should be initialized by OpenRoot and closed by Close.
+type Root struct {
+	Path string
+	// Perm is used for created files and directories.
+	// OpenRoot sets it to DefaultPerm.
+	Perm Perm
+	fd   int
+}
+
+// OpenRoot opens directory as Root.
+// If directory doesn't exist, then returns an error.
+func OpenRoot(path string) (*Root, error) {
+	if !IsDirExists(path) {
+		return nil, fmt.Errorf("dir doesn't exist (%v)", path)
+	}
+
+	fd, err := openRootFD(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Root{Path: path, Perm: DefaultPerm, fd: fd}, nil
+}
+
+// Close closes the root.
+func (r *Root) Close() error {
+	return r.close()
+}
+
+// splitRootPath splits relative path into components.
+// Empty and "." components are dropped.
+func splitRootPath(name string) []string {
+	var parts []string
+	for _, part := range strings.Split(filepath.ToSlash(name), "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// escapeError returns error for path which escapes the root.
+func escapeError(name string) error {
+	return fmt.Errorf("%v: %w", name, ErrPathEscapes)
+}
+
+// OpenFile opens a file inside the root like os.OpenFile does.
+func (r *Root) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return r.openFile(name, flag, perm)
+}
+
+// Open opens a file inside the root for reading.
+func (r *Root) Open(name string) (*os.File, error) {
+	return r.openFile(name, os.O_RDONLY, 0)
+}
+
+// Stat returns information about a file inside the root.
+// Symlinks are followed, if they don't escape the root.
+func (r *Root) Stat(name string) (os.FileInfo, error) {
+	return r.stat(name)
+}
+
+// IsFileExists checks file existence inside the root.
+func (r *Root) IsFileExists(name string) bool {
+	_, err := r.stat(name)
+	return err == nil
+}
+
+// GetFileContent returns slice of content from file inside the root.
+// Every element of slice marked as one line.
+// Binary files are read as text, use GetTextFileContent to refuse them.
+// If there's an error, function returns nil and error.
+func (r *Root) GetFileContent(name string) (FileLines, error) {
+	file, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return scanLines(file)
+}
+
+// GetTextFileContent works same as GetFileContent,
+// but returns *BinaryFileError if file looks binary.
+func (r *Root) GetTextFileContent(name string) (FileLines, error) {
+	file, err := r.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	text, err := sniffText(name, file)
+	if err != nil {
+		return nil, err
+	}
+	return scanLines(text)
+}
+
+// CreateFileQ creates a file inside the root.
+// If the file already exists, then returns an error.
+func (r *Root) CreateFileQ(name string) (*File, error) {
+	return r.CreateFileW(name, FileLines{""})
+}
+
+// CreateFileW creates a file inside the root,
+// then writes content to the file.
+// Every element of content is a new line.
+// If the file already exists, then returns an error.
+func (r *Root) CreateFileW(name string, content FileLines) (*File, error) {
+	file, err := r.createExclusive(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if err := writeLines(file, content); err != nil {
+		return nil, err
+	}
+
+	return newFile(file.Name(), content), nil
+}
+
+// WriteContent writes content to file inside the root.
+// If it couldn't, returns error.
+func (r *Root) WriteContent(name string, content FileLines) error {
+	file, err := r.openFile(name, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return writeLines(file, content)
+}
+
+// AppendToFile appends content to an existing file inside the root.
+// If the file doesn't exist, returns an error.
+func (r *Root) AppendToFile(name string, content FileLines) error {
+	file, err := r.openFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("file %v does not exist", name)
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return writeLines(file, content)
+}
+
+// RemoveFileQ removes a file inside the root.
+// If it couldn't find the file, then returns an error.
+func (r *Root) RemoveFileQ(name string) error {
+	err := r.remove(name, false)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("file %v does not exist", name)
+	}
+	return err
+}
+
+// RenameFile renames a file inside the root from oldName to newName.
+// If the newName already exists, returns an error.
+func (r *Root) RenameFile(oldName, newName string) error {
+	if err := r.rename(oldName, newName); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("file %v already exists", newName)
+		}
+		return err
+	}
+	return nil
+}
+
+// CopyFile copies a file inside the root from source to destination.
+// If the destination file already exists, returns an error.
+func (r *Root) CopyFile(source, destination string) error {
+	input, err := r.Open(source)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	output, err := r.createExclusive(destination)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(output, input)
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// createExclusive creates a file inside the root with mode from r.Perm,
+// same as createExclusive does.
+func (r *Root) createExclusive(name string) (*os.File, error) {
+	file, err := r.openFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, r.Perm.File)
+	if os.IsExist(err) {
+		return nil, &existsError{name}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := applyModeFD(file, r.Perm.File, r.Perm.IgnoreUmask); err != nil {
+		_ = file.Close()
+		_ = r.remove(name, false)
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// CreateDir creates directory inside the root with mode from r.Perm.
+// If mode differs from requested, directory is removed
+// and *PermError is returned.
+func (r *Root) CreateDir(name string) error {
+	if err := r.mkdir(name, r.Perm.Dir); err != nil {
+		return err
+	}
+
+	if err := r.applyDirPerm(name); err != nil {
+		_ = r.remove(name, true)
+		return err
+	}
+	return nil
+}
+
+// CreateDirQ creates directory inside the root with all parents.
+// Created directories get mode from r.Perm.
+func (r *Root) CreateDirQ(name string) error {
+	parts := splitRootPath(name)
+	for i := range parts {
+		dir := filepath.Join(parts[:i+1]...)
+		err := r.mkdir(dir, r.Perm.Dir)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := r.applyDirPerm(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyDirPerm sets mode of created directory inside the root
+// if umask is ignored, then verifies it.
+func (r *Root) applyDirPerm(name string) error {
+	dir, err := r.openFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	_, err = applyModeFD(dir, r.Perm.Dir, r.Perm.IgnoreUmask)
+	return err
+}
+
+// ReadDirQ reads directory inside the root and returns Dir object.
+// Symlinks aren't followed and are listed as files.
+// Found elements append to slice in this format:
+//
+// Directory format: dtest
+//
+// File format: ftest.txt
+//
+// If there's error, returns nil and error.
+func (r *Root) ReadDirQ(name string) (*Dir, error) {
+	path := filepath.Join(r.Path, name)
+	children := []string{"d" + path}
+
+	if err := r.readDir(name, path, &children); err != nil {
+		return nil, err
+	}
+
+	return &Dir{Path: path, Children: children}, nil
+}
+
+// readDir appends children of directory name to children.
+func (r *Root) readDir(name, path string, children *[]string) error {
+	dir, err := r.Open(name)
+	if err != nil {
+		return err
+	}
+
+	entries, err := dir.ReadDir(-1)
+	_ = dir.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		location := filepath.Join(path, entry.Name())
+		if !entry.IsDir() {
+			*children = append(*children, "f"+location)
+			continue
+		}
+
+		*children = append(*children, "d"+location)
+		if err := r.readDir(filepath.Join(name, entry.Name()), location, children); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveDirQ removes a directory inside the root with all its content.
+// Symlinks inside the directory are removed, not followed.
+func (r *Root) RemoveDirQ(name string) error {
+	if len(splitRootPath(name)) == 0 {
+		return fmt.Errorf("can't remove root %v", r.Path)
+	}
+
+	info, err := r.stat(name)
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("dir doesn't exists: (%v)", name)
+	}
+
+	return r.removeAll(name)
+}
+
+// RemoveEmptyDir removes an empty directory inside the root.
+// Returns an error if the directory is not empty or does not exist.
+func (r *Root) RemoveEmptyDir(name string) error {
+	err := r.remove(name, true)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("directory %v does not exist", name)
+	}
+	return err
+}
+
+// MoveDir moves a directory inside the root
+// from sourceName to destinationName.
+// If the destination directory already exists, returns an error.
+func (r *Root) MoveDir(sourceName, destinationName string) error {
+	if err := r.rename(sourceName, destinationName); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("directory %v already exists", destinationName)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package fs_utils
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	_AT_REMOVEDIR = 0x200
+	_O_PATH       = 0x200000
+
+	// maxRootSymlinks is maximum count of symlinks followed
+	// while resolving one path, same as Linux kernel uses.
+	maxRootSymlinks = 40
+)
+
+// openRootFD opens directory for Root.
+func openRootFD(path string) (int, error) {
+	fd, err := syscall.Open(path, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return fd, nil
+}
+
+// close closes root descriptor.
+func (r *Root) close() error {
+	if r.fd < 0 {
+		return nil
+	}
+
+	err := syscall.Close(r.fd)
+	r.fd = -1
+	return err
+}
+
+// resolve walks name component by component with openat,
+// never following symlinks implicitly.
+// Symlinks are read and their targets are resolved the same way,
+// so a path can't leave the root through "..", absolute
+// or relative symlinks.
+// Returns descriptor of parent directory and last component.
+// If followLast is set, symlink in last component is followed too.
+// Descriptor should be closed by caller.
+func (r *Root) resolve(name string, followLast bool) (int, string, error) {
+	if filepath.IsAbs(name) {
+		return -1, "", escapeError(name)
+	}
+
+	var stack []int
+	current := func() int {
+		if len(stack) == 0 {
+			return r.fd
+		}
+		return stack[len(stack)-1]
+	}
+	cleanup := func() {
+		for _, fd := range stack {
+			_ = syscall.Close(fd)
+		}
+	}
+
+	parts := splitRootPath(name)
+	links := 0
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+
+		if part == ".." {
+			if len(stack) == 0 {
+				cleanup()
+				return -1, "", escapeError(name)
+			}
+			_ = syscall.Close(stack[len(stack)-1])
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		last := len(parts) == 0
+		if !last || followLast {
+			if target, ok := readlinkat(current(), part); ok {
+				links++
+				if links > maxRootSymlinks {
+					cleanup()
+					return -1, "", &os.PathError{Op: "openat", Path: name, Err: syscall.ELOOP}
+				}
+				if filepath.IsAbs(target) {
+					cleanup()
+					return -1, "", escapeError(name)
+				}
+				parts = append(splitRootPath(target), parts...)
+				continue
+			}
+		}
+
+		if last {
+			fd, err := syscall.Dup(current())
+			cleanup()
+			if err != nil {
+				return -1, "", err
+			}
+			return fd, part, nil
+		}
+
+		fd, err := syscall.Openat(current(), part, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			cleanup()
+			return -1, "", &os.PathError{Op: "openat", Path: name, Err: err}
+		}
+		stack = append(stack, fd)
+	}
+
+	// Path resolves to a directory itself, like "." or "a/..".
+	fd, err := syscall.Dup(current())
+	cleanup()
+	if err != nil {
+		return -1, "", err
+	}
+	return fd, ".", nil
+}
+
+// readlinkat returns target of symlink.
+// Returns false if file isn't a symlink or doesn't exist.
+func readlinkat(dirfd int, name string) (string, bool) {
+	path, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return "", false
+	}
+
+	for size := 256; ; size *= 2 {
+		buf := make([]byte, size)
+		n, _, errno := syscall.Syscall6(syscall.SYS_READLINKAT, uintptr(dirfd),
+			uintptr(unsafe.Pointer(path)), uintptr(unsafe.Pointer(&buf[0])), uintptr(size), 0, 0)
+		if errno != 0 {
+			return "", false
+		}
+		if int(n) < size {
+			return string(buf[:n]), true
+		}
+	}
+}
+
+// unlinkat removes file or empty directory relative to dirfd.
+func unlinkat(dirfd int, name string, flags int) error {
+	path, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_UNLINKAT, uintptr(dirfd), uintptr(unsafe.Pointer(path)), uintptr(flags))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// openFile opens file inside the root.
+func (r *Root) openFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	dirfd, base, err := r.resolve(name, true)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.Close(dirfd)
+
+	fd, err := syscall.Openat(dirfd, base, flag|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, uint32(perm.Perm()))
+	if err != nil {
+		return nil, &os.PathError{Op: "openat", Path: name, Err: err}
+	}
+
+	return os.NewFile(uintptr(fd), filepath.Join(r.Path, name)), nil
+}
+
+// stat returns information about file inside the root.
+func (r *Root) stat(name string) (os.FileInfo, error) {
+	file, err := r.openFile(name, _O_PATH, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return file.Stat()
+}
+
+// mkdir creates directory inside the root.
+func (r *Root) mkdir(name string, perm os.FileMode) error {
+	dirfd, base, err := r.resolve(name, false)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(dirfd)
+
+	if err := syscall.Mkdirat(dirfd, base, uint32(perm.Perm())); err != nil {
+		return &os.PathError{Op: "mkdirat", Path: name, Err: err}
+	}
+	return nil
+}
+
+// remove removes file or empty directory inside the root.
+// Symlink in last component is removed itself.
+func (r *Root) remove(name string, dir bool) error {
+	dirfd, base, err := r.resolve(name, false)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(dirfd)
+
+	flags := 0
+	if dir {
+		flags = _AT_REMOVEDIR
+	}
+
+	if err := unlinkat(dirfd, base, flags); err != nil {
+		return &os.PathError{Op: "unlinkat", Path: name, Err: err}
+	}
+	return nil
+}
+
+// removeAll removes file or directory inside the root with all its content.
+func (r *Root) removeAll(name string) error {
+	dirfd, base, err := r.resolve(name, false)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(dirfd)
+
+	if err := removeAllAt(dirfd, base); err != nil {
+		return &os.PathError{Op: "unlinkat", Path: name, Err: err}
+	}
+	return nil
+}
+
+// removeAllAt removes name relative to dirfd with all its content.
+// Directories are opened with O_NOFOLLOW, so symlinks are never followed.
+func removeAllAt(dirfd int, name string) error {
+	err := unlinkat(dirfd, name, 0)
+	if err == nil || err == syscall.ENOENT {
+		return nil
+	}
+	if err != syscall.EISDIR && err != syscall.EPERM {
+		return err
+	}
+
+	fd, err := syscall.Openat(dirfd, name, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+
+	dir := os.NewFile(uintptr(fd), name)
+	names, err := dir.Readdirnames(-1)
+	if err == nil {
+		for _, child := range names {
+			if err = removeAllAt(fd, child); err != nil {
+				break
+			}
+		}
+	}
+	_ = dir.Close()
+	if err != nil {
+		return err
+	}
+
+	return unlinkat(dirfd, name, _AT_REMOVEDIR)
+}
+
+// rename renames file inside the root,
+// but never replaces existing newName.
+// Uses renameat2 with RENAME_NOREPLACE. Where it isn't supported,
+// falls back to linkatNoReplace.
+func (r *Root) rename(oldName, newName string) error {
+	info, err := r.stat(oldName)
+	if err != nil {
+		return err
+	}
+
+	oldfd, oldBase, err := r.resolve(oldName, false)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(oldfd)
+
+	newfd, newBase, err := r.resolve(newName, false)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(newfd)
+
+	err = renameat2(oldfd, oldBase, newfd, newBase, _RENAME_NOREPLACE)
+	if noReplaceUnsupported(err) {
+		err = linkatNoReplace(oldfd, oldBase, newfd, newBase, info.IsDir())
+	}
+	if err != nil {
+		return &os.LinkError{Op: "renameat", Old: oldName, New: newName, Err: err}
+	}
+	return nil
+}
+
+// linkatNoReplace works same as linkNoReplace,
+// but names are relative to directory descriptors.
+func linkatNoReplace(oldfd int, oldBase string, newfd int, newBase string, dir bool) error {
+	if dir {
+		if err := syscall.Mkdirat(newfd, newBase, 0700); err != nil {
+			return err
+		}
+		if err := syscall.Renameat(oldfd, oldBase, newfd, newBase); err != nil {
+			_ = unlinkat(newfd, newBase, _AT_REMOVEDIR)
+			return err
+		}
+		return nil
+	}
+
+	if err := linkat(oldfd, oldBase, newfd, newBase); err != nil {
+		return err
+	}
+	return unlinkat(oldfd, oldBase, 0)
+}
+
+// linkat creates hardlink relative to directory descriptors.
+// Symlinks aren't followed.
+func linkat(oldfd int, oldName string, newfd int, newName string) error {
+	oldPath, err := syscall.BytePtrFromString(oldName)
+	if err != nil {
+		return err
+	}
+	newPath, err := syscall.BytePtrFromString(newName)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall6(syscall.SYS_LINKAT, uintptr(oldfd), uintptr(unsafe.Pointer(oldPath)),
+		uintptr(newfd), uintptr(unsafe.Pointer(newPath)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
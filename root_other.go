@@ -0,0 +1,42 @@
+//go:build !linux
+
+package fs_utils
+
+import (
+	"errors"
+	"os"
+)
+
+// openRootFD opens directory for Root.
+// Openat-style resolution is only implemented on Linux.
+func openRootFD(path string) (int, error) {
+	return -1, errors.ErrUnsupported
+}
+
+func (r *Root) close() error {
+	return nil
+}
+
+func (r *Root) openFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (r *Root) stat(name string) (os.FileInfo, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (r *Root) mkdir(name string, perm os.FileMode) error {
+	return errors.ErrUnsupported
+}
+
+func (r *Root) remove(name string, dir bool) error {
+	return errors.ErrUnsupported
+}
+
+func (r *Root) removeAll(name string) error {
+	return errors.ErrUnsupported
+}
+
+func (r *Root) rename(oldName, newName string) error {
+	return errors.ErrUnsupported
+}
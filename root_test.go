@@ -0,0 +1,207 @@
+//go:build linux
+
+package fs_utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestRootFiles(t *testing.T) {
+	tempDir := t.TempDir()
+	root, err := OpenRoot(tempDir)
+	if err != nil {
+		t.Fatalf("expected to open root: %v, error: %v", tempDir, err)
+	}
+	defer root.Close()
+
+	// Test creating a directory and a file inside the root
+	if err := root.CreateDirQ("sub/dir"); err != nil {
+		t.Fatalf("expected to create directory, error: %v", err)
+	}
+	if _, err := root.CreateFileW("sub/dir/test.txt", FileLines{"HI!", "BYE!"}); err != nil {
+		t.Fatalf("expected to create file, error: %v", err)
+	}
+
+	// Test creating an existing file
+	if _, err := root.CreateFileW("sub/dir/test.txt", nil); err == nil {
+		t.Errorf("expected error when creating existing file")
+	}
+
+	// Test reading the file
+	lines, err := root.GetFileContent("sub/dir/../dir/test.txt")
+	if err != nil || len(lines) != 2 || lines[1] != "BYE!" {
+		t.Errorf("expected to read file content, got: %v, error: %v", lines, err)
+	}
+
+	// Test reading the directory
+	d, err := root.ReadDirQ("sub")
+	if err != nil {
+		t.Fatalf("expected to read directory, error: %v", err)
+	}
+	expected := "f" + filepath.Join(tempDir, "sub", "dir", "test.txt")
+	found := false
+	for _, child := range d.Children {
+		if child == expected {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected element: %v to be found in %v", expected, d.Children)
+	}
+
+	// Test removing the directory
+	if err := root.RemoveDirQ("sub"); err != nil {
+		t.Errorf("expected to remove directory, error: %v", err)
+	}
+	if IsDirExists(filepath.Join(tempDir, "sub")) {
+		t.Errorf("expected directory to not exist")
+	}
+}
+
+func TestRootEscape(t *testing.T) {
+	outside := t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	os.WriteFile(secret, []byte("secret"), 0644)
+
+	tempDir := t.TempDir()
+	os.Mkdir(filepath.Join(tempDir, "inner"), os.ModePerm)
+	os.Symlink(outside, filepath.Join(tempDir, "absolute"))
+	os.Symlink("../../"+filepath.Base(outside), filepath.Join(tempDir, "inner", "relative"))
+	os.Symlink("inner", filepath.Join(tempDir, "allowed"))
+
+	root, err := OpenRoot(tempDir)
+	if err != nil {
+		t.Fatalf("expected to open root: %v, error: %v", tempDir, err)
+	}
+	defer root.Close()
+
+	escapes := []string{
+		"../secret.txt",
+		"inner/../../secret.txt",
+		secret,
+		"absolute/secret.txt",
+		"inner/relative/secret.txt",
+	}
+	for _, name := range escapes {
+		if _, err := root.GetFileContent(name); !errors.Is(err, ErrPathEscapes) {
+			t.Errorf("expected escape error for %v, got: %v", name, err)
+		}
+		if _, err := root.CreateFileW(name, nil); !errors.Is(err, ErrPathEscapes) {
+			t.Errorf("expected escape error for %v, got: %v", name, err)
+		}
+	}
+
+	// Test symlink which stays inside the root
+	if _, err := root.CreateFileW("allowed/test.txt", FileLines{"test"}); err != nil {
+		t.Errorf("expected to create file through symlink inside root, error: %v", err)
+	}
+	if !IsFileExists(filepath.Join(tempDir, "inner", "test.txt")) {
+		t.Errorf("expected file to be created inside root")
+	}
+
+	// Test removing directory with symlink to outside
+	if err := root.RemoveDirQ("inner"); err != nil {
+		t.Errorf("expected to remove directory, error: %v", err)
+	}
+	if !IsFileExists(secret) {
+		t.Errorf("expected file outside root to stay: %v", secret)
+	}
+}
+
+func TestRootPerm(t *testing.T) {
+	tempDir := t.TempDir()
+	root, err := OpenRoot(tempDir)
+	if err != nil {
+		t.Fatalf("expected to open root: %v, error: %v", tempDir, err)
+	}
+	defer root.Close()
+
+	// Test creating with exact modes
+	root.Perm = Perm{File: 0640, Dir: 0750, IgnoreUmask: true}
+	if err := root.CreateDirQ("a/b"); err != nil {
+		t.Fatalf("expected to create directory, error: %v", err)
+	}
+	if _, err := root.CreateFileW("a/b/test.txt", FileLines{"test"}); err != nil {
+		t.Fatalf("expected to create file, error: %v", err)
+	}
+
+	if info, _ := os.Stat(filepath.Join(tempDir, "a")); info.Mode().Perm() != 0750 {
+		t.Errorf("expected directory mode to be 0750, got: %v", info.Mode())
+	}
+	if info, _ := os.Stat(filepath.Join(tempDir, "a", "b", "test.txt")); info.Mode().Perm() != 0640 {
+		t.Errorf("expected file mode to be 0640, got: %v", info.Mode())
+	}
+}
+
+func TestRootRename(t *testing.T) {
+	tempDir := t.TempDir()
+	root, err := OpenRoot(tempDir)
+	if err != nil {
+		t.Fatalf("expected to open root: %v, error: %v", tempDir, err)
+	}
+	defer root.Close()
+
+	root.CreateFileW("a.txt", FileLines{"a"})
+	root.CreateFileW("b.txt", FileLines{"b"})
+	root.CreateDirQ("dir/sub")
+	root.CreateDirQ("other")
+
+	// Test renaming to existing file and directory
+	if err := root.RenameFile("a.txt", "b.txt"); err == nil {
+		t.Errorf("expected error for existing file")
+	}
+	if err := root.MoveDir("dir", "other"); err == nil {
+		t.Errorf("expected error for existing directory")
+	}
+
+	// Test renaming
+	if err := root.RenameFile("a.txt", "dir/a.txt"); err != nil {
+		t.Errorf("expected to rename file, error: %v", err)
+	}
+	if err := root.MoveDir("dir", "moved"); err != nil {
+		t.Errorf("expected to move directory, error: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(tempDir, "moved", "a.txt")); string(content) != "a\n" {
+		t.Errorf("expected file to be moved with directory, got: %q", content)
+	}
+
+	// Test fallback without renameat2
+	dirfd, err := openRootFD(tempDir)
+	if err != nil {
+		t.Fatalf("expected to open directory: %v, error: %v", tempDir, err)
+	}
+	defer syscall.Close(dirfd)
+	if err := linkatNoReplace(dirfd, "b.txt", dirfd, "moved/a.txt", false); !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected fallback to refuse existing file, got: %v", err)
+	}
+	if err := linkatNoReplace(dirfd, "moved", dirfd, "other", true); !errors.Is(err, os.ErrExist) {
+		t.Errorf("expected fallback to refuse existing directory, got: %v", err)
+	}
+	if err := linkatNoReplace(dirfd, "b.txt", dirfd, "c.txt", false); err != nil || IsFileExists(filepath.Join(tempDir, "b.txt")) {
+		t.Errorf("expected fallback to rename file, error: %v", err)
+	}
+}
+
+func TestRootGetTextFileContent(t *testing.T) {
+	tempDir := t.TempDir()
+	os.WriteFile(filepath.Join(tempDir, "test.txt"), []byte("test\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "test.png"), []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"), 0644)
+
+	root, err := OpenRoot(tempDir)
+	if err != nil {
+		t.Fatalf("expected to open root: %v, error: %v", tempDir, err)
+	}
+	defer root.Close()
+
+	// Test reading inside root
+	if _, err := root.GetTextFileContent("test.png"); !errors.Is(err, ErrBinaryFile) {
+		t.Errorf("expected Root.GetTextFileContent to refuse binary file, got: %v", err)
+	}
+	if lines, err := root.GetTextFileContent("test.txt"); err != nil || len(lines) != 1 {
+		t.Errorf("expected to read text file inside root, got: %v, error: %v", lines, err)
+	}
+}
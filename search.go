@@ -0,0 +1,234 @@
+package fs_utils
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SearchOptions configures Search.
+type SearchOptions struct {
+	IgnoreCase bool
+	// FixedString makes pattern a plain string instead of regexp.
+	FixedString bool
+	// Include and Exclude are glob patterns, matched against
+	// slash-separated path relative to root and against base name.
+	// If Include is set, only matching files are searched.
+	// Excluded directories are skipped with their content.
+	Include []string
+	Exclude []string
+	// MaxCount limits number of matching lines per file, 0 means no limit.
+	MaxCount int
+	// Context is number of lines kept before and after matching line.
+	Context int
+	// Workers is number of files searched concurrently.
+	// If 0, then runtime.NumCPU is used.
+	Workers int
+	// ReportBinary reports skipped binary files
+	// by *BinaryFileError in returned error.
+	ReportBinary bool
+}
+
+// SearchMatch is a line which matches pattern.
+// Line and Column start from 1, Column is byte offset of first match.
+type SearchMatch struct {
+	Path   string
+	Line   int
+	Column int
+	Text   string
+	Before []string
+	After  []string
+}
+
+// Search searches files under root for lines matching pattern,
+// like grep -r does. Binary files are skipped,
+// set opts.ReportBinary to get them reported.
+// Matches are sorted by path and line.
+// Files which couldn't be read are reported by error,
+// matches from other files are still returned.
+func Search(root, pattern string, opts SearchOptions) ([]SearchMatch, error) {
+	re, err := compilePattern(pattern, opts.FixedString, opts.IgnoreCase)
+	if err != nil {
+		return nil, err
+	}
+
+	files, walkErr := filterFiles(root, opts.Include, opts.Exclude)
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	results := make([][]SearchMatch, len(files))
+	errs := make([]error, len(files))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i], errs[i] = searchFile(files[i], re, opts)
+			}
+		}()
+	}
+	for i := range files {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var matches []SearchMatch
+	for _, result := range results {
+		matches = append(matches, result...)
+	}
+
+	return matches, errors.Join(append([]error{walkErr}, errs...)...)
+}
+
+// compilePattern compiles search pattern.
+func compilePattern(pattern string, fixed, ignoreCase bool) (*regexp.Regexp, error) {
+	if fixed {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// filterFiles returns sorted regular files under root,
+// which are allowed by include and exclude patterns.
+// Excluded directories aren't walked.
+// Entries which couldn't be read are reported by error,
+// files found under other entries are still returned.
+func filterFiles(root string, include, exclude []string) ([]string, error) {
+	var files []string
+	var errs []error
+	_ = filepath.Walk(root, func(location string, info fs.FileInfo, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, location)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+
+		if info.IsDir() {
+			if rel != "." && matchAny(exclude, rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if rel == "." {
+			rel = filepath.Base(location)
+		}
+		if len(include) > 0 && !matchAny(include, rel) {
+			return nil
+		}
+		if matchAny(exclude, rel) {
+			return nil
+		}
+
+		files = append(files, location)
+		return nil
+	})
+
+	sort.Strings(files)
+	return files, errors.Join(errs...)
+}
+
+// searchFile searches single file.
+// File is read line by line, and only leading bytes
+// are read from binary files.
+func searchFile(path string, re *regexp.Regexp, opts SearchOptions) ([]SearchMatch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	text, err := sniffText(path, file)
+	if errors.Is(err, ErrBinaryFile) && !opts.ReportBinary {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(text)
+
+	var matches []SearchMatch
+	var before []string
+	// pending are indexes of matches, which wait for lines after them.
+	var pending []int
+	limited := false
+
+	for number := 1; ; number++ {
+		line, err := reader.ReadString('\n')
+		if line == "" {
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		waiting := pending[:0]
+		for _, i := range pending {
+			matches[i].After = append(matches[i].After, line)
+			if len(matches[i].After) < opts.Context {
+				waiting = append(waiting, i)
+			}
+		}
+		pending = waiting
+
+		if limited {
+			if len(pending) == 0 {
+				break
+			}
+			continue
+		}
+
+		if loc := re.FindStringIndex(line); loc != nil {
+			match := SearchMatch{Path: path, Line: number, Column: loc[0] + 1, Text: line}
+			if opts.Context > 0 {
+				match.Before = append([]string(nil), before...)
+				pending = append(pending, len(matches))
+			}
+			matches = append(matches, match)
+			limited = opts.MaxCount > 0 && len(matches) == opts.MaxCount
+		}
+
+		if opts.Context > 0 {
+			before = append(before, line)
+			if len(before) > opts.Context {
+				before = before[1:]
+			}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,127 @@
+package fs_utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	tempDir := t.TempDir()
+	os.Mkdir(filepath.Join(tempDir, "sub"), os.ModePerm)
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("one\nTODO: two\nthree\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "sub", "b.go"), []byte("x := 1 // todo\ny := 2 // TODO\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "bin.dat"), []byte("TODO\x00binary"), 0644)
+
+	// Test case-sensitive search
+	matches, err := Search(tempDir, "TODO", SearchOptions{Context: 1})
+	if err != nil {
+		t.Fatalf("expected to search: %v, error: %v", tempDir, err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got: %v", matches)
+	}
+	first := matches[0]
+	if first.Path != filepath.Join(tempDir, "a.txt") || first.Line != 2 || first.Column != 1 {
+		t.Errorf("expected match at a.txt:2:1, got: %v:%v:%v", first.Path, first.Line, first.Column)
+	}
+	if !reflect.DeepEqual(first.Before, []string{"one"}) || !reflect.DeepEqual(first.After, []string{"three"}) {
+		t.Errorf("expected context lines, got: %v, %v", first.Before, first.After)
+	}
+
+	// Test reporting of binary files
+	var binaryErr *BinaryFileError
+	if matches, err := Search(tempDir, "TODO", SearchOptions{ReportBinary: true}); len(matches) != 2 || !errors.As(err, &binaryErr) ||
+		binaryErr.Path != filepath.Join(tempDir, "bin.dat") {
+		t.Errorf("expected binary file to be reported, got: %v, error: %v", matches, err)
+	}
+
+	// Test case-insensitive search with file filter and max count
+	opts := SearchOptions{IgnoreCase: true, Include: []string{"*.go"}, MaxCount: 1}
+	matches, _ = Search(tempDir, "todo", opts)
+	if len(matches) != 1 || matches[0].Line != 1 || matches[0].Column != 11 {
+		t.Errorf("expected single match at line 1, column 11, got: %v", matches)
+	}
+
+	// Test fixed string
+	matches, _ = Search(tempDir, "x := 1", SearchOptions{FixedString: true, Exclude: []string{"*.txt"}})
+	if len(matches) != 1 {
+		t.Errorf("expected single match, got: %v", matches)
+	}
+}
+
+func TestSearchInvalidPattern(t *testing.T) {
+	if _, err := Search(t.TempDir(), "(", SearchOptions{}); err == nil {
+		t.Errorf("expected error for invalid pattern")
+	}
+}
+
+func TestSearchContext(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "a.txt")
+	os.WriteFile(path, []byte("a\nmatch 1\nb\nmatch 2\r\nc\nd\n"), 0644)
+
+	// Test overlapping context of close matches
+	matches, err := Search(tempDir, "match", SearchOptions{Context: 2})
+	if err != nil {
+		t.Fatalf("expected to search: %v, error: %v", tempDir, err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got: %v", matches)
+	}
+	if !reflect.DeepEqual(matches[0].Before, []string{"a"}) || !reflect.DeepEqual(matches[0].After, []string{"b", "match 2"}) {
+		t.Errorf("expected context of first match, got: %v, %v", matches[0].Before, matches[0].After)
+	}
+	if !reflect.DeepEqual(matches[1].Before, []string{"match 1", "b"}) || !reflect.DeepEqual(matches[1].After, []string{"c", "d"}) {
+		t.Errorf("expected context of second match, got: %v, %v", matches[1].Before, matches[1].After)
+	}
+	if matches[1].Text != "match 2" {
+		t.Errorf("expected line ending to be trimmed, got: %q", matches[1].Text)
+	}
+
+	// Test context after last match, when max count is reached
+	matches, _ = Search(tempDir, "match", SearchOptions{Context: 1, MaxCount: 1})
+	if len(matches) != 1 || !reflect.DeepEqual(matches[0].After, []string{"b"}) {
+		t.Errorf("expected single match with context, got: %v", matches)
+	}
+}
+
+func TestSearchExcludeDir(t *testing.T) {
+	tempDir := t.TempDir()
+	os.Mkdir(filepath.Join(tempDir, "vendor"), os.ModePerm)
+	os.WriteFile(filepath.Join(tempDir, "a.go"), []byte("TODO\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "vendor", "b.go"), []byte("TODO\n"), 0644)
+
+	matches, err := Search(tempDir, "TODO", SearchOptions{Exclude: []string{"vendor"}})
+	if err != nil {
+		t.Fatalf("expected to search: %v, error: %v", tempDir, err)
+	}
+	if len(matches) != 1 || matches[0].Path != filepath.Join(tempDir, "a.go") {
+		t.Errorf("expected excluded directory to be skipped, got: %v", matches)
+	}
+}
+
+func TestSearchUnreadableDir(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() == 0 {
+		t.Skip("permissions aren't enforced")
+	}
+
+	tempDir := t.TempDir()
+	locked := filepath.Join(tempDir, "locked")
+	os.Mkdir(locked, os.ModePerm)
+	os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("TODO\n"), 0644)
+	os.Chmod(locked, 0)
+	defer os.Chmod(locked, 0755)
+
+	// Test matches from other files are returned with error
+	matches, err := Search(tempDir, "TODO", SearchOptions{})
+	if err == nil {
+		t.Errorf("expected error for unreadable directory")
+	}
+	if len(matches) != 1 {
+		t.Errorf("expected match from readable file, got: %v", matches)
+	}
+}
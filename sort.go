@@ -0,0 +1,314 @@
+package fs_utils
+
+import (
+	"bufio"
+	"container/heap"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultMemoryLimit is memory budget of SortFile, if it isn't set.
+const defaultMemoryLimit = 64 << 20
+
+// SortOptions configures sorting of lines.
+type SortOptions struct {
+	// Less compares lines. If set, Numeric and Key are ignored.
+	Less func(a, b string) bool
+	// Numeric compares leading numbers of keys.
+	// Keys without numbers go first.
+	Numeric bool
+	// Reverse sorts in descending order.
+	Reverse bool
+	// Key is 1-based number of whitespace-separated field used as sort key.
+	// If 0, then whole line is used.
+	Key int
+	// MemoryLimit is approximate number of bytes kept in memory by SortFile.
+	// If 0, then 64 MiB is used.
+	MemoryLimit int
+	// TempDir is directory for sorted runs, see os.CreateTemp.
+	TempDir string
+}
+
+// less returns comparator built from options.
+// Equal keys are ordered by whole line, so order doesn't depend on input.
+func (opts SortOptions) less() func(a, b string) bool {
+	less := opts.Less
+	if less == nil {
+		less = func(a, b string) bool {
+			keyA, keyB := sortKey(a, opts.Key), sortKey(b, opts.Key)
+			if opts.Numeric {
+				numberA, okA := leadingNumber(keyA)
+				numberB, okB := leadingNumber(keyB)
+				if okA != okB {
+					return !okA
+				}
+				if numberA != numberB {
+					return numberA < numberB
+				}
+			}
+			if keyA != keyB {
+				return keyA < keyB
+			}
+			return a < b
+		}
+	}
+
+	if opts.Reverse {
+		return func(a, b string) bool {
+			return less(b, a)
+		}
+	}
+	return less
+}
+
+// sortKey returns field of line, which is used as sort key.
+func sortKey(line string, key int) string {
+	if key <= 0 {
+		return line
+	}
+
+	fields := strings.Fields(line)
+	if key > len(fields) {
+		return ""
+	}
+	return fields[key-1]
+}
+
+// leadingNumber parses number at start of s, like sort -n does.
+func leadingNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && ((s[end] >= '0' && s[end] <= '9') || s[end] == '.' || (end == 0 && s[end] == '-')) {
+		end++
+	}
+
+	number, err := strconv.ParseFloat(s[:end], 64)
+	return number, err == nil
+}
+
+// Sort sorts lines in memory.
+func (fl FileLines) Sort(opts SortOptions) {
+	less := opts.less()
+	sort.SliceStable(fl, func(i, j int) bool {
+		return less(fl[i], fl[j])
+	})
+}
+
+// Unique removes adjacent duplicate lines, like uniq does.
+// Sort lines first to remove all duplicates.
+func (fl *FileLines) Unique() {
+	if len(*fl) == 0 {
+		return
+	}
+
+	result := (*fl)[:1]
+	for _, line := range (*fl)[1:] {
+		if line != result[len(result)-1] {
+			result = append(result, line)
+		}
+	}
+	*fl = result
+}
+
+// SortFile sorts lines of source file and writes them to destination,
+// which can be same file. Lines are read in chunks, which fit
+// into opts.MemoryLimit, every chunk is sorted and written
+// to temporary file, then temporary files are merged.
+func SortFile(source, destination string, opts SortOptions) error {
+	return sortFile(source, destination, opts, false)
+}
+
+// UniqFile works same as SortFile, but removes duplicate lines.
+func UniqFile(source, destination string, opts SortOptions) error {
+	return sortFile(source, destination, opts, true)
+}
+
+// sortFile sorts file with external merge sort.
+// If unique is set, duplicate lines are removed.
+func sortFile(source, destination string, opts SortOptions, unique bool) error {
+	limit := opts.MemoryLimit
+	if limit <= 0 {
+		limit = defaultMemoryLimit
+	}
+
+	input, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	var runs []string
+	defer func() {
+		for _, run := range runs {
+			_ = os.Remove(run)
+		}
+	}()
+
+	reader := bufio.NewReader(input)
+	var chunk FileLines
+	size := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			chunk = append(chunk, line)
+			// Every line also costs its string header in slice.
+			size += len(line) + 16
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if size >= limit || (err == io.EOF && len(runs) > 0 && len(chunk) > 0) {
+			run, runErr := writeRun(chunk, opts, unique)
+			if runErr != nil {
+				return runErr
+			}
+			runs = append(runs, run)
+			chunk, size = nil, 0
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	// Source is closed, so it can be replaced when it's also destination.
+	info, err := input.Stat()
+	if err != nil {
+		return err
+	}
+	mode := info.Mode().Perm()
+	if info, err := os.Stat(destination); err == nil {
+		mode = info.Mode().Perm()
+	}
+	_ = input.Close()
+
+	return writeAtomic(destination, mode, func(w io.Writer) error {
+		// Small input is sorted without temporary files.
+		if len(runs) == 0 {
+			chunk.Sort(opts)
+			if unique {
+				chunk.Unique()
+			}
+			return writeLines(w, chunk)
+		}
+		return mergeRuns(w, runs, opts.less(), unique)
+	})
+}
+
+// writeRun sorts lines and writes them to temporary file.
+func writeRun(lines FileLines, opts SortOptions, unique bool) (string, error) {
+	lines.Sort(opts)
+	if unique {
+		lines.Unique()
+	}
+
+	file, err := os.CreateTemp(opts.TempDir, "fs-utils-sort-")
+	if err != nil {
+		return "", err
+	}
+
+	err = writeLines(file, lines)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// mergeRun is a sorted run, which is being merged.
+type mergeRun struct {
+	reader *bufio.Reader
+	line   string
+}
+
+// mergeHeap is a heap of runs ordered by their current lines.
+type mergeHeap struct {
+	runs []*mergeRun
+	less func(a, b string) bool
+}
+
+func (h *mergeHeap) Len() int           { return len(h.runs) }
+func (h *mergeHeap) Less(i, j int) bool { return h.less(h.runs[i].line, h.runs[j].line) }
+func (h *mergeHeap) Swap(i, j int)      { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+func (h *mergeHeap) Push(x any)         { h.runs = append(h.runs, x.(*mergeRun)) }
+func (h *mergeHeap) Pop() any {
+	run := h.runs[len(h.runs)-1]
+	h.runs = h.runs[:len(h.runs)-1]
+	return run
+}
+
+// next reads next line of run.
+// Returns false, if run is finished.
+func (r *mergeRun) next() (bool, error) {
+	line, err := r.reader.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return false, nil
+	}
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	r.line = strings.TrimSuffix(line, "\n")
+	return true, nil
+}
+
+// mergeRuns merges sorted runs with k-way merge and writes lines to w.
+// If unique is set, duplicate lines are written once.
+func mergeRuns(w io.Writer, paths []string, less func(a, b string) bool, unique bool) error {
+	h := &mergeHeap{less: less}
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+
+		run := &mergeRun{reader: bufio.NewReader(file)}
+		ok, err := run.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.runs = append(h.runs, run)
+		}
+	}
+	heap.Init(h)
+
+	writer := bufio.NewWriter(w)
+	written, last := false, ""
+	for h.Len() > 0 {
+		run := h.runs[0]
+		if !unique || !written || run.line != last {
+			if _, err := writer.WriteString(run.line + "\n"); err != nil {
+				return err
+			}
+			written, last = true, run.line
+		}
+
+		ok, err := run.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,67 @@
+package fs_utils
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFileLinesSort(t *testing.T) {
+	lines := FileLines{"b 10", "a 9", "c 100", "a 9"}
+
+	lines.Sort(SortOptions{Key: 2, Numeric: true})
+	if !reflect.DeepEqual(lines, FileLines{"a 9", "a 9", "b 10", "c 100"}) {
+		t.Errorf("expected numeric sort by second field, got: %v", lines)
+	}
+
+	lines.Sort(SortOptions{Reverse: true})
+	lines.Unique()
+	if !reflect.DeepEqual(lines, FileLines{"c 100", "b 10", "a 9"}) {
+		t.Errorf("expected reverse unique lines, got: %v", lines)
+	}
+}
+
+func TestSortFile(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "input.txt")
+
+	random := rand.New(rand.NewSource(1))
+	var lines []string
+	for i := 0; i < 5000; i++ {
+		lines = append(lines, fmt.Sprintf("line %v", random.Intn(1000)))
+	}
+	os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+
+	// Small memory limit makes many sorted runs
+	opts := SortOptions{MemoryLimit: 4096, TempDir: tempDir}
+
+	sorted := filepath.Join(tempDir, "sorted.txt")
+	if err := SortFile(path, sorted, opts); err != nil {
+		t.Fatalf("expected to sort file: %v, error: %v", path, err)
+	}
+	expected := append([]string(nil), lines...)
+	sort.Strings(expected)
+	if content, _ := os.ReadFile(sorted); string(content) != strings.Join(expected, "\n")+"\n" {
+		t.Errorf("expected file to be sorted")
+	}
+
+	// Test unique lines in place
+	if err := UniqFile(path, path, opts); err != nil {
+		t.Fatalf("expected to uniq file: %v, error: %v", path, err)
+	}
+	unique := FileLines(expected)
+	unique.Unique()
+	if content, _ := os.ReadFile(path); string(content) != strings.Join(unique, "\n")+"\n" {
+		t.Errorf("expected file to contain unique sorted lines")
+	}
+
+	// Test temporary files are removed
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 2 {
+		t.Errorf("expected temporary runs to be removed, got %v entries", len(entries))
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !unix
+
+package fs_utils
+
+import "io/fs"
+
+// fileDevice returns ID of device where file is located.
+// Returns false if it couldn't be determined.
+func fileDevice(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
+
+// fileID returns device and inode of file,
+// which are same for hardlinks of one file.
+// Returns false if they couldn't be determined.
+func fileID(info fs.FileInfo) ([2]uint64, bool) {
+	return [2]uint64{}, false
+}
+
+// fileOwner returns owner and group of file.
+// Returns false if they couldn't be determined.
+func fileOwner(info fs.FileInfo) (int, int, bool) {
+	return 0, 0, false
+}
+
+// fileLinks returns number of hardlinks of file.
+// Returns false if it couldn't be determined.
+func fileLinks(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package fs_utils
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileDevice returns ID of device where file is located.
+// Returns false if it couldn't be determined.
+func fileDevice(info fs.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true
+}
+
+// fileID returns device and inode of file,
+// which are same for hardlinks of one file.
+// Returns false if they couldn't be determined.
+func fileID(info fs.FileInfo) ([2]uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return [2]uint64{}, false
+	}
+	return [2]uint64{uint64(stat.Dev), uint64(stat.Ino)}, true
+}
+
+// fileOwner returns owner and group of file.
+// Returns false if they couldn't be determined.
+func fileOwner(info fs.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// fileLinks returns number of hardlinks of file.
+// Returns false if it couldn't be determined.
+func fileLinks(info fs.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Nlink), true
+}
@@ -0,0 +1,380 @@
+package fs_utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SyncOptions configures Sync.
+type SyncOptions struct {
+	// Checksum compares files by content instead of size and modification time.
+	Checksum bool
+	// Delete removes files and directories from destination
+	// which don't exist in source.
+	Delete bool
+	// DryRun reports what would be done without touching destination.
+	DryRun bool
+	// Include is a list of glob patterns. If it isn't empty,
+	// only files matching at least one pattern are synced.
+	Include []string
+	// Exclude is a list of glob patterns. Matching files and directories
+	// are neither synced nor deleted.
+	Exclude []string
+}
+
+// SyncResult contains paths (relative to source and destination)
+// which were handled by Sync.
+// Skipped contains unchanged files and entries
+// filtered out by Include and Exclude.
+type SyncResult struct {
+	Copied  []string
+	Updated []string
+	Deleted []string
+	Skipped []string
+}
+
+// Sync mirrors directory src to directory dst, like rsync does.
+// New files are copied, changed files are updated and unchanged
+// files are skipped. Files are compared by size and modification time,
+// or by content if opts.Checksum is set.
+// If destination doesn't exist, then it's created.
+// If Include is set, directories are created only for included files.
+// Modes of created directories are set after their content is copied,
+// so read-only directories are synced too.
+// If there's an error, returns result collected so far and error.
+func Sync(src, dst string, opts SyncOptions) (*SyncResult, error) {
+	if !IsDirExists(src) {
+		return nil, fmt.Errorf("dir doesn't exist (%v)", src)
+	}
+
+	result := &SyncResult{}
+	seen := make(map[string]bool)
+	dirs := &syncDirs{dst: dst, modes: make(map[string]fs.FileMode)}
+
+	err := filepath.Walk(src, func(location string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, location)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			dirs.modes[rel] = info.Mode().Perm()
+			if opts.DryRun {
+				return nil
+			}
+			return dirs.ensure(rel)
+		}
+
+		if matchAny(opts.Exclude, rel) {
+			result.Skipped = append(result.Skipped, rel)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		seen[rel] = true
+		target := filepath.Join(dst, rel)
+
+		if info.IsDir() {
+			dirs.modes[rel] = info.Mode().Perm()
+			if opts.DryRun || len(opts.Include) > 0 {
+				return nil
+			}
+			return dirs.ensure(rel)
+		}
+
+		if len(opts.Include) > 0 && !matchAny(opts.Include, rel) {
+			result.Skipped = append(result.Skipped, rel)
+			return nil
+		}
+
+		targetInfo, err := os.Lstat(target)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if targetInfo != nil {
+			same, err := isSameFile(location, info, target, targetInfo, opts.Checksum)
+			if err != nil {
+				return err
+			}
+			if same {
+				result.Skipped = append(result.Skipped, rel)
+				return nil
+			}
+		}
+
+		if !opts.DryRun {
+			if err := dirs.ensure(filepath.Dir(rel)); err != nil {
+				return err
+			}
+			if err := syncEntry(location, info, target); err != nil {
+				return err
+			}
+		}
+
+		if targetInfo == nil {
+			result.Copied = append(result.Copied, rel)
+		} else {
+			result.Updated = append(result.Updated, rel)
+		}
+		return nil
+	})
+
+	if modeErr := dirs.applyModes(); err == nil {
+		err = modeErr
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if opts.Delete && IsDirExists(dst) {
+		deleted, err := deleteExtraneous(dst, seen, opts)
+		result.Deleted = deleted
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// syncDirs creates destination directories for Sync.
+// Directories are created writable by owner,
+// their modes are applied by applyModes.
+type syncDirs struct {
+	dst     string
+	modes   map[string]fs.FileMode
+	created []string
+}
+
+// ensure creates directory rel and its parents in destination,
+// if they don't exist. Non-directory entries are replaced
+// and read-only directories are made writable until applyModes.
+func (d *syncDirs) ensure(rel string) error {
+	if rel != "." {
+		if err := d.ensure(filepath.Dir(rel)); err != nil {
+			return err
+		}
+	}
+
+	target := filepath.Join(d.dst, rel)
+	if targetInfo, err := os.Lstat(target); err == nil {
+		switch {
+		case !targetInfo.IsDir():
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		case targetInfo.Mode().Perm()&0700 == 0700:
+			return nil
+		default:
+			// Read-only directory of previous sync.
+			if err := os.Chmod(target, targetInfo.Mode().Perm()|0700); err != nil {
+				return err
+			}
+			d.created = append(d.created, rel)
+			return nil
+		}
+	}
+
+	if err := os.MkdirAll(target, d.modes[rel]|0700); err != nil {
+		return err
+	}
+	d.created = append(d.created, rel)
+	return nil
+}
+
+// applyModes sets modes of source directories to created directories.
+// Children are changed before parents.
+func (d *syncDirs) applyModes() error {
+	var errs []error
+	for i := len(d.created) - 1; i >= 0; i-- {
+		rel := d.created[i]
+		if err := os.Chmod(filepath.Join(d.dst, rel), d.modes[rel]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deleteExtraneous removes entries of dst which weren't seen in source.
+// Returns relative paths of removed entries.
+func deleteExtraneous(dst string, seen map[string]bool, opts SyncOptions) ([]string, error) {
+	var extraneous []string
+	err := filepath.Walk(dst, func(location string, info fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dst, location)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		if matchAny(opts.Exclude, rel) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !seen[rel] {
+			extraneous = append(extraneous, rel)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(extraneous)
+
+	var deleted []string
+	for _, rel := range extraneous {
+		if !opts.DryRun {
+			if err := os.RemoveAll(filepath.Join(dst, rel)); err != nil {
+				return deleted, err
+			}
+		}
+		deleted = append(deleted, rel)
+	}
+
+	return deleted, nil
+}
+
+// matchAny reports whether rel or its base name matches one of patterns.
+func matchAny(patterns []string, rel string) bool {
+	slashed := filepath.ToSlash(rel)
+	base := filepath.Base(rel)
+
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, slashed); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, base); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSameFile compares source and target files.
+// Symlinks are compared by their targets.
+func isSameFile(source string, sourceInfo fs.FileInfo, target string, targetInfo fs.FileInfo, checksum bool) (bool, error) {
+	if sourceInfo.Mode().Type() != targetInfo.Mode().Type() {
+		return false, nil
+	}
+
+	if sourceInfo.Mode()&os.ModeSymlink != 0 {
+		sourceLink, err := os.Readlink(source)
+		if err != nil {
+			return false, err
+		}
+		targetLink, err := os.Readlink(target)
+		if err != nil {
+			return false, err
+		}
+		return sourceLink == targetLink, nil
+	}
+
+	if sourceInfo.Size() != targetInfo.Size() {
+		return false, nil
+	}
+
+	if !checksum {
+		return sourceInfo.ModTime().Equal(targetInfo.ModTime()), nil
+	}
+
+	sourceSum, err := hashFile(source, SHA256)
+	if err != nil {
+		return false, err
+	}
+	targetSum, err := hashFile(target, SHA256)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(sourceSum, targetSum), nil
+}
+
+// syncEntry copies a file or a symlink from source to target,
+// replacing target if it exists.
+func syncEntry(source string, info fs.FileInfo, target string) error {
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(source)
+		if err != nil {
+			return err
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+	}
+
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("can't sync %v: not a regular file", source)
+	}
+
+	return replaceFile(source, info, target)
+}
+
+// replaceFile copies source to target through temporary file,
+// so target is never left half-written.
+// Keeps permissions and modification time of source.
+func replaceFile(source string, info fs.FileInfo, target string) error {
+	input, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	temp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*")
+	if err != nil {
+		return err
+	}
+	tempPath := temp.Name()
+
+	_, err = io.Copy(temp, input)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempPath, info.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Chtimes(tempPath, info.ModTime(), info.ModTime())
+	}
+	if err == nil {
+		if targetInfo, statErr := os.Lstat(target); statErr == nil && targetInfo.IsDir() {
+			err = os.RemoveAll(target)
+		}
+	}
+	if err == nil {
+		err = os.Rename(tempPath, target)
+	}
+
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	return nil
+}
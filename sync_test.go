@@ -0,0 +1,194 @@
+package fs_utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSync(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "mirror")
+
+	// Create source tree
+	os.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644)
+	os.Mkdir(filepath.Join(src, "sub"), os.ModePerm)
+	os.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("b"), 0644)
+
+	// Test first sync
+	result, err := Sync(src, dst, SyncOptions{})
+	if err != nil {
+		t.Fatalf("expected to sync: %v, error: %v", src, err)
+	}
+	if len(result.Copied) != 2 {
+		t.Errorf("expected 2 copied files, got: %v", result.Copied)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dst, "sub", "b.txt"))
+	if err != nil || string(content) != "b" {
+		t.Errorf("expected file to be copied, got: %q, error: %v", content, err)
+	}
+
+	// Test second sync without changes
+	result, err = Sync(src, dst, SyncOptions{})
+	if err != nil {
+		t.Fatalf("expected to sync: %v, error: %v", src, err)
+	}
+	if len(result.Skipped) != 2 || len(result.Copied) != 0 || len(result.Updated) != 0 {
+		t.Errorf("expected all files to be skipped, got: %+v", result)
+	}
+
+	// Test updating a changed file
+	later := time.Now().Add(time.Hour)
+	os.WriteFile(filepath.Join(src, "a.txt"), []byte("changed"), 0644)
+	os.Chtimes(filepath.Join(src, "a.txt"), later, later)
+
+	result, err = Sync(src, dst, SyncOptions{})
+	if err != nil {
+		t.Fatalf("expected to sync: %v, error: %v", src, err)
+	}
+	if len(result.Updated) != 1 || result.Updated[0] != "a.txt" {
+		t.Errorf("expected a.txt to be updated, got: %v", result.Updated)
+	}
+}
+
+func TestSyncDelete(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	os.WriteFile(filepath.Join(src, "keep.txt"), []byte("keep"), 0644)
+	os.WriteFile(filepath.Join(dst, "extra.txt"), []byte("extra"), 0644)
+	os.WriteFile(filepath.Join(dst, "ignored.log"), []byte("log"), 0644)
+
+	// Test dry run
+	result, err := Sync(src, dst, SyncOptions{Delete: true, DryRun: true, Exclude: []string{"*.log"}})
+	if err != nil {
+		t.Fatalf("expected to sync: %v, error: %v", src, err)
+	}
+	if len(result.Deleted) != 1 || result.Deleted[0] != "extra.txt" {
+		t.Errorf("expected extra.txt to be deleted, got: %v", result.Deleted)
+	}
+	if !IsFileExists(filepath.Join(dst, "extra.txt")) || IsFileExists(filepath.Join(dst, "keep.txt")) {
+		t.Errorf("expected dry run to leave destination untouched")
+	}
+
+	// Test real run
+	_, err = Sync(src, dst, SyncOptions{Delete: true, Exclude: []string{"*.log"}})
+	if err != nil {
+		t.Fatalf("expected to sync: %v, error: %v", src, err)
+	}
+	if IsFileExists(filepath.Join(dst, "extra.txt")) {
+		t.Errorf("expected extraneous file to be deleted")
+	}
+	if !IsFileExists(filepath.Join(dst, "ignored.log")) {
+		t.Errorf("expected excluded file to be kept")
+	}
+	if !IsFileExists(filepath.Join(dst, "keep.txt")) {
+		t.Errorf("expected file to be copied")
+	}
+}
+
+func TestSyncChecksum(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	// Same size and modification time, but different content
+	now := time.Now()
+	os.WriteFile(filepath.Join(src, "a.txt"), []byte("aaa"), 0644)
+	os.WriteFile(filepath.Join(dst, "a.txt"), []byte("bbb"), 0644)
+	os.Chtimes(filepath.Join(src, "a.txt"), now, now)
+	os.Chtimes(filepath.Join(dst, "a.txt"), now, now)
+
+	result, err := Sync(src, dst, SyncOptions{})
+	if err != nil {
+		t.Fatalf("expected to sync: %v, error: %v", src, err)
+	}
+	if len(result.Skipped) != 1 {
+		t.Errorf("expected file to be skipped by size and time, got: %+v", result)
+	}
+
+	result, err = Sync(src, dst, SyncOptions{Checksum: true})
+	if err != nil {
+		t.Fatalf("expected to sync: %v, error: %v", src, err)
+	}
+	if len(result.Updated) != 1 {
+		t.Errorf("expected file to be updated by checksum, got: %+v", result)
+	}
+}
+
+func TestSyncInclude(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	os.WriteFile(filepath.Join(src, "a.go"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(src, "b.txt"), []byte("b"), 0644)
+
+	result, err := Sync(src, dst, SyncOptions{Include: []string{"*.go"}})
+	if err != nil {
+		t.Fatalf("expected to sync: %v, error: %v", src, err)
+	}
+	if len(result.Copied) != 1 || result.Copied[0] != "a.go" {
+		t.Errorf("expected only a.go to be copied, got: %v", result.Copied)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0] != "b.txt" {
+		t.Errorf("expected b.txt to be skipped, got: %v", result.Skipped)
+	}
+}
+
+func TestSyncIncludeDirs(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	os.MkdirAll(filepath.Join(src, "code", "pkg"), os.ModePerm)
+	os.Mkdir(filepath.Join(src, "docs"), os.ModePerm)
+	os.WriteFile(filepath.Join(src, "code", "pkg", "a.go"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(src, "docs", "b.txt"), []byte("b"), 0644)
+
+	_, err := Sync(src, dst, SyncOptions{Include: []string{"*.go"}})
+	if err != nil {
+		t.Fatalf("expected to sync: %v, error: %v", src, err)
+	}
+	if !IsFileExists(filepath.Join(dst, "code", "pkg", "a.go")) {
+		t.Errorf("expected included file to be copied")
+	}
+	if IsDirExists(filepath.Join(dst, "docs")) {
+		t.Errorf("expected directory without included files not to be created")
+	}
+}
+
+func TestSyncReadOnlyDir(t *testing.T) {
+	src := t.TempDir()
+	dst := filepath.Join(t.TempDir(), "mirror")
+
+	os.Mkdir(filepath.Join(src, "ro"), os.ModePerm)
+	os.WriteFile(filepath.Join(src, "ro", "a.txt"), []byte("a"), 0644)
+	os.Chmod(filepath.Join(src, "ro"), 0555)
+	t.Cleanup(func() {
+		os.Chmod(filepath.Join(src, "ro"), 0755)
+		os.Chmod(filepath.Join(dst, "ro"), 0755)
+	})
+
+	if _, err := Sync(src, dst, SyncOptions{}); err != nil {
+		t.Fatalf("expected to sync read-only directory, error: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dst, "ro"))
+	if err != nil {
+		t.Fatalf("expected directory to be created, error: %v", err)
+	}
+	if info.Mode().Perm() != 0555 {
+		t.Errorf("expected directory mode 0555, got: %v", info.Mode().Perm())
+	}
+
+	// Test syncing new file into read-only directory of previous sync
+	os.Chmod(filepath.Join(src, "ro"), 0755)
+	os.WriteFile(filepath.Join(src, "ro", "b.txt"), []byte("b"), 0644)
+	os.Chmod(filepath.Join(src, "ro"), 0555)
+
+	if _, err := Sync(src, dst, SyncOptions{}); err != nil {
+		t.Fatalf("expected to sync read-only directory again, error: %v", err)
+	}
+	if !IsFileExists(filepath.Join(dst, "ro", "b.txt")) {
+		t.Errorf("expected new file to be copied")
+	}
+}
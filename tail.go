@@ -0,0 +1,281 @@
+package fs_utils
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// tailChunk is size of blocks read from the end of file by Tail.
+const tailChunk = 4096
+
+// Tail returns last n lines of file.
+// File is read backwards from its end, so only needed part is read.
+func Tail(path string, n int) (FileLines, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return tailFile(file, info.Size(), n)
+}
+
+// tailFile returns last n lines of file, which ends at size.
+func tailFile(file *os.File, size int64, n int) (FileLines, error) {
+	if n <= 0 || size == 0 {
+		return nil, nil
+	}
+
+	// Chunks are collected from the end and joined once.
+	var chunks [][]byte
+	end := size
+	newlines := 0
+	for end > 0 {
+		start := max(0, end-tailChunk)
+		chunk := make([]byte, end-start)
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+
+		if end == size {
+			// Newline at the end of file doesn't start a new line.
+			chunk = bytes.TrimSuffix(chunk, []byte("\n"))
+		}
+		end = start
+
+		newlines += bytes.Count(chunk, []byte("\n"))
+		if newlines >= n {
+			break
+		}
+	}
+
+	slices.Reverse(chunks)
+	lines := splitLines(string(bytes.Join(chunks, nil)))
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// endsWithNewline reports whether file of size is empty
+// or its last byte is newline.
+func endsWithNewline(file *os.File, size int64) (bool, error) {
+	if size == 0 {
+		return true, nil
+	}
+
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, size-1); err != nil {
+		return false, err
+	}
+	return last[0] == '\n', nil
+}
+
+// FollowOptions configures Follow.
+type FollowOptions struct {
+	// Lines is number of existing last lines, which are sent first.
+	Lines int
+	// Poll is interval between checks of the file.
+	// If 0, then 250ms is used.
+	Poll time.Duration
+}
+
+// Follower sends lines appended to file, like tail -F does.
+// Should be initialized by Follow.
+type Follower struct {
+	Path  string
+	lines chan string
+	err   error
+	file  *os.File
+	info  os.FileInfo
+	// offset is position in file up to which it was read.
+	offset  int64
+	partial []byte
+}
+
+// Follow watches file at path and sends new lines to channel,
+// which is returned by Lines.
+// Truncated file is read again from its start.
+// When file is replaced, for example by log rotation,
+// rest of old file is read and then new file is followed.
+// Stops when ctx is cancelled.
+func Follow(ctx context.Context, path string, opts FollowOptions) (*Follower, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	complete, err := endsWithNewline(file, info.Size())
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	n := opts.Lines
+	if !complete {
+		// Incomplete last line is held until its end is written.
+		n++
+	}
+	initial, err := tailFile(file, info.Size(), n)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	var partial []byte
+	if !complete && len(initial) > 0 {
+		partial = []byte(initial[len(initial)-1])
+		initial = initial[:len(initial)-1]
+	}
+
+	poll := opts.Poll
+	if poll <= 0 {
+		poll = 250 * time.Millisecond
+	}
+
+	f := &Follower{Path: path, lines: make(chan string), file: file, info: info, offset: info.Size(), partial: partial}
+	go f.run(ctx, initial, poll)
+	return f, nil
+}
+
+// Lines returns channel with lines of file.
+// Channel is closed when following stops.
+func (f *Follower) Lines() <-chan string {
+	return f.lines
+}
+
+// Err returns error, which stopped following.
+// Should be called after channel from Lines is closed.
+// Cancellation of context isn't an error.
+func (f *Follower) Err() error {
+	return f.err
+}
+
+// run follows file until ctx is cancelled or error occurs.
+func (f *Follower) run(ctx context.Context, initial FileLines, poll time.Duration) {
+	defer close(f.lines)
+	defer func() {
+		_ = f.file.Close()
+	}()
+
+	for _, line := range initial {
+		if !f.send(ctx, line) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		if err := f.check(ctx); err != nil {
+			f.err = err
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check reads new content of file and handles truncation and rotation.
+func (f *Follower) check(ctx context.Context) error {
+	info, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < f.offset {
+		// File was truncated.
+		f.offset = 0
+		f.partial = nil
+	}
+
+	if err := f.read(ctx); err != nil {
+		return err
+	}
+
+	current, err := os.Stat(f.Path)
+	if os.IsNotExist(err) {
+		// File was moved away and new one isn't created yet.
+		return nil
+	}
+	if err != nil || os.SameFile(current, f.info) {
+		return err
+	}
+
+	// File was replaced, so rest of old file is read first.
+	if err := f.read(ctx); err != nil {
+		return err
+	}
+	if len(f.partial) > 0 {
+		f.send(ctx, string(f.partial))
+	}
+
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return err
+	}
+	info, err = file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	_ = f.file.Close()
+	f.file, f.info, f.offset, f.partial = file, info, 0, nil
+	return f.read(ctx)
+}
+
+// read sends complete lines appended after offset.
+// Incomplete last line is kept until its end is written.
+func (f *Follower) read(ctx context.Context) error {
+	data, err := io.ReadAll(io.NewSectionReader(f.file, f.offset, 1<<62))
+	if err != nil {
+		return err
+	}
+	f.offset += int64(len(data))
+
+	data = append(f.partial, data...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		if !f.send(ctx, string(bytes.TrimSuffix(data[:i], []byte("\r")))) {
+			return nil
+		}
+		data = data[i+1:]
+	}
+	f.partial = append([]byte(nil), data...)
+
+	return nil
+}
+
+// send sends line, unless ctx is cancelled.
+func (f *Follower) send(ctx context.Context, line string) bool {
+	select {
+	case f.lines <- line:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,117 @@
+package fs_utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+
+	var builder strings.Builder
+	for i := 1; i <= 10000; i++ {
+		fmt.Fprintf(&builder, "line %v\n", i)
+	}
+	os.WriteFile(path, []byte(builder.String()), 0644)
+
+	lines, err := Tail(path, 3)
+	if err != nil {
+		t.Fatalf("expected to read tail of: %v, error: %v", path, err)
+	}
+	if !reflect.DeepEqual(lines, FileLines{"line 9998", "line 9999", "line 10000"}) {
+		t.Errorf("expected last 3 lines, got: %v", lines)
+	}
+
+	// Test file shorter than n
+	os.WriteFile(path, []byte("a\nb"), 0644)
+	if lines, _ := Tail(path, 5); !reflect.DeepEqual(lines, FileLines{"a", "b"}) {
+		t.Errorf("expected all lines, got: %v", lines)
+	}
+}
+
+// receiveLine waits for line from follower.
+func receiveLine(t *testing.T, f *Follower) string {
+	t.Helper()
+	select {
+	case line := <-f.Lines():
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected line from follower")
+		return ""
+	}
+}
+
+func TestFollow(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.log")
+	os.WriteFile(path, []byte("old 1\nold 2\n"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f, err := Follow(ctx, path, FollowOptions{Lines: 1, Poll: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("expected to follow: %v, error: %v", path, err)
+	}
+	if line := receiveLine(t, f); line != "old 2" {
+		t.Errorf("expected last existing line, got: %v", line)
+	}
+
+	// Test appended lines
+	AppendToFile(path, FileLines{"new 1"})
+	if line := receiveLine(t, f); line != "new 1" {
+		t.Errorf("expected appended line, got: %v", line)
+	}
+
+	// Test truncation
+	os.WriteFile(path, []byte("truncated\n"), 0644)
+	if line := receiveLine(t, f); line != "truncated" {
+		t.Errorf("expected line after truncation, got: %v", line)
+	}
+
+	// Test rotation
+	os.Rename(path, path+".1")
+	os.WriteFile(path, []byte("rotated\n"), 0644)
+	if line := receiveLine(t, f); line != "rotated" {
+		t.Errorf("expected line from new file, got: %v", line)
+	}
+
+	// Test cancellation
+	cancel()
+	for range f.Lines() {
+	}
+	if err := f.Err(); err != nil {
+		t.Errorf("expected no error after cancellation, got: %v", err)
+	}
+}
+
+// Test incomplete last line at start of following
+func TestFollowPartialLine(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.log")
+	os.WriteFile(path, []byte("old 1\nold 2\npart"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f, err := Follow(ctx, path, FollowOptions{Lines: 1, Poll: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("expected to follow: %v, error: %v", path, err)
+	}
+	if line := receiveLine(t, f); line != "old 2" {
+		t.Errorf("expected last complete line, got: %v", line)
+	}
+
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	file.WriteString("ial\n")
+	file.Close()
+	if line := receiveLine(t, f); line != "partial" {
+		t.Errorf("expected joined line, got: %v", line)
+	}
+}
@@ -0,0 +1,140 @@
+package fs_utils
+
+import (
+	"os"
+)
+
+// TempOptions configures CreateTempFile and CreateTempDir.
+type TempOptions struct {
+	// Dir is a parent directory. If empty, then os.TempDir is used.
+	Dir string
+	// Pattern is a name pattern, last "*" is replaced by random string.
+	// If there is no "*", random string is appended.
+	Pattern string
+	// KeepOnFailure keeps files when Cleanup is called after failure,
+	// so they can be inspected.
+	KeepOnFailure bool
+}
+
+// Cleaner registers cleanup functions and reports failure.
+// It is implemented by testing.TB.
+type Cleaner interface {
+	Cleanup(func())
+	Failed() bool
+	Logf(format string, args ...any)
+}
+
+// TempFile is a temporary file, which is removed by Close or Cleanup.
+// Should be initialized by CreateTempFile.
+type TempFile struct {
+	File
+	keepOnFailure bool
+}
+
+// TempDir is a temporary directory, which is removed
+// with its content by Close or Cleanup.
+// Should be initialized by CreateTempDir.
+type TempDir struct {
+	Dir
+	keepOnFailure bool
+}
+
+// CreateTempFile creates a new temporary file with content.
+// Every element of content is a new line.
+// File is created with mode 0600.
+func CreateTempFile(opts TempOptions, content FileLines) (*TempFile, error) {
+	file, err := os.CreateTemp(opts.Dir, opts.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeLines(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+
+	stamp, _ := statStamp(file.Name())
+	f := &TempFile{File{file.Name(), content}, opts.KeepOnFailure}
+	trackFile(f, &f.File, stamp)
+	return f, nil
+}
+
+// CreateTempDir creates a new temporary directory.
+// Directory is created with mode 0700.
+func CreateTempDir(opts TempOptions) (*TempDir, error) {
+	path, err := os.MkdirTemp(opts.Dir, opts.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TempDir{Dir{Path: path}, opts.KeepOnFailure}, nil
+}
+
+// Close removes the file.
+// Removing already removed file isn't an error.
+func (f *TempFile) Close() error {
+	return f.Cleanup(false)
+}
+
+// Cleanup removes the file, unless failed is set
+// and file was created with KeepOnFailure.
+func (f *TempFile) Cleanup(failed bool) error {
+	if failed && f.keepOnFailure {
+		return nil
+	}
+
+	err := os.Remove(f.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Register makes c remove the file when it finishes.
+// Kept files are logged by c.
+func (f *TempFile) Register(c Cleaner) {
+	c.Cleanup(func() {
+		registeredCleanup(c, f.Path, f.Cleanup)
+	})
+}
+
+// Close removes the directory with its content.
+// Removing already removed directory isn't an error.
+func (d *TempDir) Close() error {
+	return d.Cleanup(false)
+}
+
+// Cleanup removes the directory with its content, unless failed is set
+// and directory was created with KeepOnFailure.
+func (d *TempDir) Cleanup(failed bool) error {
+	if failed && d.keepOnFailure {
+		return nil
+	}
+
+	return os.RemoveAll(d.Path)
+}
+
+// Register makes c remove the directory when it finishes.
+// Kept directories are logged by c.
+func (d *TempDir) Register(c Cleaner) {
+	c.Cleanup(func() {
+		registeredCleanup(c, d.Path, d.Cleanup)
+	})
+}
+
+// registeredCleanup runs cleanup registered by Register
+// and logs result to c.
+func registeredCleanup(c Cleaner, path string, cleanup func(failed bool) error) {
+	failed := c.Failed()
+	if err := cleanup(failed); err != nil {
+		c.Logf("error while removing %v: %v", path, err)
+		return
+	}
+	if _, err := os.Lstat(path); err == nil && failed {
+		c.Logf("keeping %v for debugging", path)
+	}
+}
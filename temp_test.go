@@ -0,0 +1,98 @@
+package fs_utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testing.TB should be usable as Cleaner.
+var _ Cleaner = testing.TB(nil)
+
+// fakeCleaner implements Cleaner for testing failures.
+type fakeCleaner struct {
+	cleanups []func()
+	failed   bool
+	logs     []string
+}
+
+func (c *fakeCleaner) Cleanup(f func()) { c.cleanups = append(c.cleanups, f) }
+func (c *fakeCleaner) Failed() bool     { return c.failed }
+func (c *fakeCleaner) Logf(format string, args ...any) {
+	c.logs = append(c.logs, fmt.Sprintf(format, args...))
+}
+
+func (c *fakeCleaner) finish() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		c.cleanups[i]()
+	}
+}
+
+func TestCreateTempFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	file, err := CreateTempFile(TempOptions{Dir: tempDir, Pattern: "test-*.txt"}, FileLines{"a", "b"})
+	if err != nil {
+		t.Fatalf("expected to create temp file, error: %v", err)
+	}
+
+	name := filepath.Base(file.Path)
+	if !strings.HasPrefix(name, "test-") || !strings.HasSuffix(name, ".txt") {
+		t.Errorf("expected name to match pattern, got: %v", name)
+	}
+	content, _ := os.ReadFile(file.Path)
+	if string(content) != "a\nb\n" {
+		t.Errorf("expected content to be written, got: %q", content)
+	}
+
+	// Test closing
+	if err := file.Close(); err != nil {
+		t.Errorf("expected to remove temp file, error: %v", err)
+	}
+	if IsFileExists(file.Path) {
+		t.Errorf("expected temp file to be removed: %v", file.Path)
+	}
+	if err := file.Close(); err != nil {
+		t.Errorf("expected second close to succeed, error: %v", err)
+	}
+}
+
+func TestTempDirRegister(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Test removal after success
+	c := &fakeCleaner{}
+	dir, err := CreateTempDir(TempOptions{Dir: tempDir, KeepOnFailure: true})
+	if err != nil {
+		t.Fatalf("expected to create temp dir, error: %v", err)
+	}
+	dir.Register(c)
+	os.WriteFile(filepath.Join(dir.Path, "test.txt"), []byte("test"), 0644)
+	c.finish()
+	if IsDirExists(dir.Path) {
+		t.Errorf("expected temp dir to be removed: %v", dir.Path)
+	}
+
+	// Test keeping on failure
+	c = &fakeCleaner{failed: true}
+	dir, _ = CreateTempDir(TempOptions{Dir: tempDir, KeepOnFailure: true})
+	dir.Register(c)
+	c.finish()
+	if !IsDirExists(dir.Path) {
+		t.Errorf("expected temp dir to be kept on failure: %v", dir.Path)
+	}
+	if len(c.logs) != 1 {
+		t.Errorf("expected kept dir to be logged, got: %v", c.logs)
+	}
+
+	// Test removal on failure without KeepOnFailure
+	c = &fakeCleaner{failed: true}
+	dir, _ = CreateTempDir(TempOptions{Dir: tempDir})
+	dir.Register(c)
+	c.finish()
+	if IsDirExists(dir.Path) {
+		t.Errorf("expected temp dir to be removed: %v", dir.Path)
+	}
+}
@@ -0,0 +1,519 @@
+package fs_utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// trashInfoExt is extension of files in trash info directory.
+const trashInfoExt = ".trashinfo"
+
+// trashDateLayout is format of DeletionDate key in .trashinfo files.
+const trashDateLayout = "2006-01-02T15:04:05"
+
+// TrashItem is a structure with information about trashed file or directory.
+// Should be initialized by Trash or ListTrash.
+// Can be restored by Restore.
+type TrashItem struct {
+	// Name is name of the item inside trash directory.
+	Name string
+	// TrashDir is trash directory which contains files and info directories.
+	TrashDir string
+	// OriginalPath is absolute path where the item was located.
+	OriginalPath string
+	// DeletionDate is time when the item was trashed.
+	DeletionDate time.Time
+}
+
+// Path returns location of the item inside trash directory.
+func (ti TrashItem) Path() string {
+	return filepath.Join(ti.TrashDir, "files", ti.Name)
+}
+
+// infoPath returns location of .trashinfo file of the item.
+func (ti TrashItem) infoPath() string {
+	return filepath.Join(ti.TrashDir, "info", ti.Name+trashInfoExt)
+}
+
+// HomeTrashDir returns home trash directory:
+// $XDG_DATA_HOME/Trash or ~/.local/share/Trash.
+func HomeTrashDir() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dataHome) {
+		return filepath.Join(dataHome, "Trash"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+// Trash moves a file or directory to trash
+// following the freedesktop.org Trash specification.
+// Files located on other mount than home trash
+// are moved to trash directory of that mount.
+// If it couldn't be created, files are copied to home trash.
+// If name is already taken in trash, then suffix is added.
+// If file doesn't exist, then returns an error.
+func Trash(path string) (*TrashItem, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Lstat(absPath); err != nil {
+		return nil, fmt.Errorf("file %v does not exist", path)
+	}
+
+	trashDir, topDir, err := trashDirFor(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sub := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(trashDir, sub), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	// Home trash keeps absolute paths,
+	// trash of other mounts keeps paths relative to top directory.
+	infoPath := absPath
+	if topDir != "" {
+		if infoPath, err = filepath.Rel(topDir, absPath); err != nil {
+			return nil, err
+		}
+	}
+
+	item := &TrashItem{
+		TrashDir:     trashDir,
+		OriginalPath: absPath,
+		DeletionDate: time.Now().Truncate(time.Second),
+	}
+
+	info, err := createTrashInfo(item, filepath.Base(absPath))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = fmt.Fprintf(info, "[Trash Info]\nPath=%v\nDeletionDate=%v\n",
+		escapeTrashPath(infoPath), item.DeletionDate.Format(trashDateLayout))
+	if closeErr := info.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = moveNoReplace(absPath, item.Path())
+	}
+
+	if err != nil {
+		_ = os.Remove(item.infoPath())
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// createTrashInfo creates .trashinfo file with unique name
+// and sets item.Name.
+// Name collisions are resolved by adding suffix: name.2, name.3, etc.
+func createTrashInfo(item *TrashItem, name string) (*os.File, error) {
+	for i := 1; ; i++ {
+		item.Name = name
+		if i > 1 {
+			item.Name = name + "." + strconv.Itoa(i)
+		}
+
+		if _, err := os.Lstat(item.Path()); err == nil {
+			continue
+		}
+
+		file, err := os.OpenFile(item.infoPath(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return file, nil
+	}
+}
+
+// trashDirFor returns trash directory for absolute path.
+// For home trash returns empty topDir,
+// otherwise topDir is top directory of path's mount.
+// If trash directory of the mount couldn't be created,
+// falls back to home trash.
+func trashDirFor(path string) (trashDir, topDir string, err error) {
+	homeTrash, err := HomeTrashDir()
+	if err != nil {
+		return "", "", err
+	}
+
+	parentInfo, err := os.Stat(filepath.Dir(path))
+	if err != nil {
+		return "", "", err
+	}
+	device, ok := fileDevice(parentInfo)
+	if !ok {
+		return homeTrash, "", nil
+	}
+
+	homeDevice, err := existingDevice(homeTrash)
+	if err != nil || homeDevice == device {
+		return homeTrash, "", nil
+	}
+
+	topDir, err = mountTopDir(filepath.Dir(path), device)
+	if err != nil {
+		return homeTrash, "", nil
+	}
+
+	uid := strconv.Itoa(os.Getuid())
+
+	// $topdir/.Trash must be a real directory with sticky bit.
+	shared := filepath.Join(topDir, ".Trash")
+	if info, err := os.Lstat(shared); err == nil && info.IsDir() && info.Mode()&os.ModeSticky != 0 {
+		trashDir = filepath.Join(shared, uid)
+		if err := os.MkdirAll(trashDir, 0700); err == nil {
+			return trashDir, topDir, nil
+		}
+	}
+
+	trashDir = filepath.Join(topDir, ".Trash-"+uid)
+	if err := os.MkdirAll(trashDir, 0700); err != nil {
+		return homeTrash, "", nil
+	}
+
+	return trashDir, topDir, nil
+}
+
+// existingDevice returns device of path,
+// or of its nearest existing parent.
+func existingDevice(path string) (uint64, error) {
+	for {
+		info, err := os.Stat(path)
+		if err == nil {
+			device, ok := fileDevice(info)
+			if !ok {
+				return 0, fmt.Errorf("couldn't get device of %v", path)
+			}
+			return device, nil
+		}
+
+		parent := filepath.Dir(path)
+		if parent == path {
+			return 0, err
+		}
+		path = parent
+	}
+}
+
+// mountTopDir returns the topmost directory above dir
+// which is located on the same device.
+func mountTopDir(dir string, device uint64) (string, error) {
+	for {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir, nil
+		}
+
+		info, err := os.Stat(parent)
+		if err != nil {
+			return "", err
+		}
+		if parentDevice, ok := fileDevice(info); !ok || parentDevice != device {
+			return dir, nil
+		}
+
+		dir = parent
+	}
+}
+
+// escapeTrashPath escapes path like URL does, keeping slashes.
+func escapeTrashPath(path string) string {
+	return (&url.URL{Path: filepath.ToSlash(path)}).EscapedPath()
+}
+
+// Restore moves trashed item back to its original location
+// and removes its .trashinfo file.
+// If original location is taken, then returns an error.
+// Location is never replaced, even if it's taken during restoring.
+func Restore(item *TrashItem) error {
+	if _, err := os.Lstat(item.OriginalPath); err == nil {
+		return fmt.Errorf("file %v already exists", item.OriginalPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(item.OriginalPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	if err := moveNoReplace(item.Path(), item.OriginalPath); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("file %v already exists", item.OriginalPath)
+		}
+		return err
+	}
+
+	return os.Remove(item.infoPath())
+}
+
+// TrashDirs returns home trash directory
+// and existing trash directories of mounted filesystems.
+func TrashDirs() ([]string, error) {
+	homeTrash, err := HomeTrashDir()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{homeTrash}
+	uid := strconv.Itoa(os.Getuid())
+
+	for _, topDir := range mountPoints() {
+		for _, dir := range []string{
+			filepath.Join(topDir, ".Trash", uid),
+			filepath.Join(topDir, ".Trash-"+uid),
+		} {
+			if dir != homeTrash && IsDirExists(filepath.Join(dir, "info")) {
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+
+	return dirs, nil
+}
+
+// mountPoints returns mount points listed in /proc/self/mounts.
+// If the list isn't available, returns nil.
+func mountPoints() []string {
+	file, err := os.Open("/proc/self/mounts")
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var points []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		// Spaces and other special characters are escaped as octal.
+		point, err := strconv.Unquote(`"` + strings.ReplaceAll(fields[1], `"`, `\"`) + `"`)
+		if err != nil {
+			point = fields[1]
+		}
+		points = append(points, point)
+	}
+
+	return points
+}
+
+// ListTrash returns items from all trash directories.
+func ListTrash() ([]TrashItem, error) {
+	dirs, err := TrashDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []TrashItem
+	for _, dir := range dirs {
+		dirItems, err := ListTrashDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, dirItems...)
+	}
+
+	return items, nil
+}
+
+// ListTrashDir returns items from specific trash directory.
+// If trash directory doesn't exist, returns nil.
+// Broken .trashinfo files are skipped.
+func ListTrashDir(trashDir string) ([]TrashItem, error) {
+	entries, err := os.ReadDir(filepath.Join(trashDir, "info"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	topDir := trashTopDir(trashDir)
+
+	var items []TrashItem
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), trashInfoExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		item, err := readTrashInfo(trashDir, name, topDir)
+		if err != nil {
+			continue
+		}
+		items = append(items, *item)
+	}
+
+	return items, nil
+}
+
+// trashTopDir returns top directory for trash directory of a mount.
+// For home trash returns empty string.
+func trashTopDir(trashDir string) string {
+	uid := strconv.Itoa(os.Getuid())
+
+	if filepath.Base(trashDir) == ".Trash-"+uid {
+		return filepath.Dir(trashDir)
+	}
+	if filepath.Base(trashDir) == uid && filepath.Base(filepath.Dir(trashDir)) == ".Trash" {
+		return filepath.Dir(filepath.Dir(trashDir))
+	}
+
+	return ""
+}
+
+// readTrashInfo reads .trashinfo file of item with specific name.
+func readTrashInfo(trashDir, name, topDir string) (*TrashItem, error) {
+	item := &TrashItem{Name: name, TrashDir: trashDir}
+
+	lines, err := GetFileContent(item.infoPath())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "[Trash Info]" {
+		return nil, fmt.Errorf("%v: invalid trash info", item.infoPath())
+	}
+
+	for _, line := range lines[1:] {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "Path":
+			path, err := url.PathUnescape(value)
+			if err != nil {
+				return nil, err
+			}
+			path = filepath.FromSlash(path)
+			if !filepath.IsAbs(path) {
+				if topDir == "" {
+					return nil, fmt.Errorf("%v: relative path in home trash", item.infoPath())
+				}
+				path = filepath.Join(topDir, path)
+			}
+			item.OriginalPath = path
+		case "DeletionDate":
+			date, err := time.ParseInLocation(trashDateLayout, value, time.Local)
+			if err != nil {
+				return nil, err
+			}
+			item.DeletionDate = date
+		}
+	}
+
+	if item.OriginalPath == "" {
+		return nil, fmt.Errorf("%v: missing path", item.infoPath())
+	}
+
+	return item, nil
+}
+
+// EmptyTrash permanently removes items from all trash directories
+// which were trashed more than olderThan ago.
+// If olderThan is 0, then removes all items.
+// Returns count of removed items.
+func EmptyTrash(olderThan time.Duration) (int, error) {
+	dirs, err := TrashDirs()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, dir := range dirs {
+		n, err := EmptyTrashDir(dir, olderThan)
+		removed += n
+		if err != nil {
+			return removed, err
+		}
+	}
+
+	return removed, nil
+}
+
+// EmptyTrashDir works same as EmptyTrash,
+// but only for specific trash directory.
+// Files without .trashinfo can't be restored,
+// so they're removed regardless of olderThan.
+func EmptyTrashDir(trashDir string, olderThan time.Duration) (int, error) {
+	items, err := ListTrashDir(trashDir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed, err := removeTrashOrphans(trashDir)
+	if err != nil {
+		return removed, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+
+	for _, item := range items {
+		if olderThan > 0 && !item.DeletionDate.Before(cutoff) {
+			continue
+		}
+
+		if err := os.RemoveAll(item.Path()); err != nil {
+			return removed, err
+		}
+		if err := os.Remove(item.infoPath()); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// removeTrashOrphans removes entries of trash files directory
+// which don't have .trashinfo file. Returns count of removed entries.
+func removeTrashOrphans(trashDir string) (int, error) {
+	entries, err := os.ReadDir(filepath.Join(trashDir, "files"))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		item := TrashItem{Name: entry.Name(), TrashDir: trashDir}
+		if _, err := os.Lstat(item.infoPath()); !os.IsNotExist(err) {
+			continue
+		}
+
+		if err := os.RemoveAll(item.Path()); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+
+	return removed, nil
+}
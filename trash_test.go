@@ -0,0 +1,202 @@
+package fs_utils
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrashAndRestore(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "file 1.txt")
+	os.WriteFile(path, []byte("test"), 0644)
+
+	// Test trashing a file
+	item, err := Trash(path)
+	if err != nil {
+		t.Fatalf("expected to trash file: %v, error: %v", path, err)
+	}
+
+	if IsFileExists(path) {
+		t.Errorf("expected file to not exist: %v", path)
+	}
+	if !IsFileExists(item.Path()) {
+		t.Errorf("expected file to be in trash: %v", item.Path())
+	}
+
+	// Verify .trashinfo file
+	info, err := os.ReadFile(item.infoPath())
+	if err != nil {
+		t.Fatalf("expected to read trash info: %v, error: %v", item.infoPath(), err)
+	}
+	if !strings.HasPrefix(string(info), "[Trash Info]\n") || !strings.Contains(string(info), "file%201.txt") {
+		t.Errorf("expected valid trash info, got: %q", info)
+	}
+
+	// Test restoring the file
+	if err := Restore(item); err != nil {
+		t.Fatalf("expected to restore file: %v, error: %v", path, err)
+	}
+	if !IsFileExists(path) {
+		t.Errorf("expected file to exist: %v", path)
+	}
+	if IsFileExists(item.infoPath()) {
+		t.Errorf("expected trash info to be removed: %v", item.infoPath())
+	}
+}
+
+func TestTrashNameCollision(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+	os.WriteFile(filepath.Join(dir1, "test.txt"), []byte("one"), 0644)
+	os.WriteFile(filepath.Join(dir2, "test.txt"), []byte("two"), 0644)
+
+	// Test trashing files with the same name
+	first, err := Trash(filepath.Join(dir1, "test.txt"))
+	if err != nil {
+		t.Fatalf("expected to trash file, error: %v", err)
+	}
+	second, err := Trash(filepath.Join(dir2, "test.txt"))
+	if err != nil {
+		t.Fatalf("expected to trash file, error: %v", err)
+	}
+
+	if first.Name == second.Name {
+		t.Errorf("expected different names in trash, got: %v", first.Name)
+	}
+
+	// Test listing trash
+	items, err := ListTrashDir(first.TrashDir)
+	if err != nil {
+		t.Fatalf("expected to list trash: %v, error: %v", first.TrashDir, err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items in trash, got: %v", items)
+	}
+
+	found := false
+	for _, item := range items {
+		if item.Name == second.Name && item.OriginalPath == second.OriginalPath {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected item: %v to be listed", second)
+	}
+}
+
+func TestEmptyTrashDir(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	tempDir := t.TempDir()
+	subdir := filepath.Join(tempDir, "subdir")
+	os.Mkdir(subdir, os.ModePerm)
+	os.WriteFile(filepath.Join(subdir, "test.txt"), []byte("test"), 0644)
+
+	item, err := Trash(subdir)
+	if err != nil {
+		t.Fatalf("expected to trash directory: %v, error: %v", subdir, err)
+	}
+
+	// Test emptying with age cutoff
+	removed, err := EmptyTrashDir(item.TrashDir, time.Hour)
+	if err != nil || removed != 0 {
+		t.Errorf("expected no items to be removed, got: %v, error: %v", removed, err)
+	}
+
+	// Test emptying all items
+	removed, err = EmptyTrashDir(item.TrashDir, 0)
+	if err != nil || removed != 1 {
+		t.Errorf("expected 1 item to be removed, got: %v, error: %v", removed, err)
+	}
+	if IsDirExists(item.Path()) {
+		t.Errorf("expected directory to not exist: %v", item.Path())
+	}
+}
+
+func TestEmptyTrashDirOrphans(t *testing.T) {
+	trashDir := t.TempDir()
+	os.MkdirAll(filepath.Join(trashDir, "files"), 0700)
+	os.MkdirAll(filepath.Join(trashDir, "info"), 0700)
+	orphan := filepath.Join(trashDir, "files", "orphan.txt")
+	os.WriteFile(orphan, []byte("test"), 0644)
+
+	// Test removing file without .trashinfo
+	removed, err := EmptyTrashDir(trashDir, time.Hour)
+	if err != nil || removed != 1 {
+		t.Errorf("expected orphan to be removed, got: %v, error: %v", removed, err)
+	}
+	if IsFileExists(orphan) {
+		t.Errorf("expected orphan to not exist: %v", orphan)
+	}
+}
+
+func TestRestoreNoReplace(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "test.txt")
+	os.WriteFile(path, []byte("trashed"), 0644)
+
+	item, err := Trash(path)
+	if err != nil {
+		t.Fatalf("expected to trash file: %v, error: %v", path, err)
+	}
+
+	// Location is taken after the check, so only rename can notice it
+	os.WriteFile(path, []byte("new"), 0644)
+	if err := renameNoReplace(item.Path(), path); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected rename to refuse existing file, got: %v", err)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "new" {
+		t.Errorf("expected existing file to be kept, got: %q", content)
+	}
+	if !IsFileExists(item.Path()) {
+		t.Errorf("expected trashed file to be kept: %v", item.Path())
+	}
+	// Test directory over empty directory
+	source, target := filepath.Join(tempDir, "source"), filepath.Join(tempDir, "target")
+	os.Mkdir(source, os.ModePerm)
+	os.Mkdir(target, os.ModePerm)
+	if err := renameNoReplace(source, target); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("expected rename to refuse existing directory, got: %v", err)
+	}
+	if !IsDirExists(source) {
+		t.Errorf("expected source directory to be kept: %v", source)
+	}
+}
+
+func TestMoveAcross(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "source")
+	os.MkdirAll(filepath.Join(source, "sub"), os.ModePerm)
+	os.WriteFile(filepath.Join(source, "sub", "test.txt"), []byte("test"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "file.txt"), []byte("file"), 0644)
+
+	// Test moving directory by copying
+	target := filepath.Join(tempDir, "target")
+	if err := moveAcross(source, target); err != nil {
+		t.Fatalf("expected to move directory: %v, error: %v", source, err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(target, "sub", "test.txt")); string(content) != "test" {
+		t.Errorf("expected file to be copied, got: %q", content)
+	}
+	if IsDirExists(source) {
+		t.Errorf("expected source to be removed: %v", source)
+	}
+
+	// Test moving file to taken location
+	if err := moveAcross(filepath.Join(tempDir, "file.txt"), filepath.Join(target, "sub", "test.txt")); err == nil {
+		t.Errorf("expected error for existing target")
+	}
+	if content, _ := os.ReadFile(filepath.Join(target, "sub", "test.txt")); string(content) != "test" {
+		t.Errorf("expected existing file to be kept, got: %q", content)
+	}
+	if !IsFileExists(filepath.Join(tempDir, "file.txt")) {
+		t.Errorf("expected source file to be kept")
+	}
+}
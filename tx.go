@@ -0,0 +1,516 @@
+package fs_utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+)
+
+// txOpKind is a kind of operation recorded in Tx.
+type txOpKind int
+
+const (
+	txCreateDir txOpKind = iota
+	txCreateFile
+	txRename
+	txCopyFile
+	txRemoveFile
+	txRemoveDir
+	txRemoveEmptyDir
+)
+
+// txOpNames are names of operations in journal log.
+var txOpNames = []string{"create-dir", "create-file", "rename", "copy-file", "remove-file", "remove-dir", "remove-empty-dir"}
+
+// txLogName is name of operation log inside journal directory.
+const txLogName = "log"
+
+// txCreatedOp is name of journal record, which follows operation
+// creating a file and keeps identity of created file.
+const txCreatedOp = "created"
+
+// txJournalPattern is pattern of journal directory names.
+const txJournalPattern = "fs-utils-tx-"
+
+// txOp is an operation recorded in Tx.
+// backup is location of removed data inside journal directory.
+// created and id identify file created by operation,
+// created is known in process, id is also read from journal.
+type txOp struct {
+	kind    txOpKind
+	path    string
+	target  string
+	content FileLines
+	backup  string
+	mode    os.FileMode
+	created fs.FileInfo
+	id      *[2]uint64
+}
+
+// txRecord is a line of journal log.
+// Every operation is recorded before it's run,
+// Commit is recorded before journal is discarded.
+type txRecord struct {
+	Op     string      `json:"op,omitempty"`
+	Path   string      `json:"path,omitempty"`
+	Target string      `json:"target,omitempty"`
+	Backup string      `json:"backup,omitempty"`
+	Mode   os.FileMode `json:"mode,omitempty"`
+	ID     *[2]uint64  `json:"id,omitempty"`
+	Commit bool        `json:"commit,omitempty"`
+}
+
+// Tx is a transactional batch of filesystem operations.
+// Operations are recorded by its methods and run in order by Apply.
+// If an operation fails, it and already completed ones are rolled back.
+// Every operation is written to journal log before it's run,
+// and removed files are kept in journal directory until Commit,
+// so a transaction interrupted by crash can be rolled back by RecoverTx.
+// Should be initialized by BeginTx.
+type Tx struct {
+	Journal string
+	dir     string
+	ops     []txOp
+	started int
+	closed  bool
+}
+
+// BeginTx starts a transaction with journal directory
+// created inside dir. If dir is empty, journal is created
+// next to path of the first operation when it's applied.
+// Journal should be on the same filesystem as changed files,
+// otherwise removed files are copied to it instead of moved.
+func BeginTx(dir string) (*Tx, error) {
+	tx := &Tx{dir: dir}
+	if dir == "" {
+		return tx, nil
+	}
+
+	if err := tx.createJournal(); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// createJournal creates journal directory.
+func (tx *Tx) createJournal() error {
+	dir := tx.dir
+	if dir == "" && len(tx.ops) > 0 {
+		dir = filepath.Dir(tx.ops[0].path)
+	}
+
+	journal, err := os.MkdirTemp(dir, txJournalPattern)
+	if err != nil {
+		return err
+	}
+
+	tx.Journal = journal
+	return nil
+}
+
+// CreateDir records creation of directory, like CreateDir does.
+func (tx *Tx) CreateDir(path string) {
+	tx.ops = append(tx.ops, txOp{kind: txCreateDir, path: path})
+}
+
+// CreateFileW records creation of file with content, like CreateFileW does.
+func (tx *Tx) CreateFileW(path string, content FileLines) {
+	tx.ops = append(tx.ops, txOp{kind: txCreateFile, path: path, content: content})
+}
+
+// RenameFile records rename of file, like RenameFile does.
+func (tx *Tx) RenameFile(oldPath, newPath string) {
+	tx.ops = append(tx.ops, txOp{kind: txRename, path: oldPath, target: newPath})
+}
+
+// MoveDir records move of directory, like MoveDir does.
+func (tx *Tx) MoveDir(sourcePath, destinationPath string) {
+	tx.ops = append(tx.ops, txOp{kind: txRename, path: sourcePath, target: destinationPath, mode: fs.ModeDir})
+}
+
+// CopyFile records copy of file, like CopyFile does.
+func (tx *Tx) CopyFile(source, destination string) {
+	tx.ops = append(tx.ops, txOp{kind: txCopyFile, path: source, target: destination})
+}
+
+// RemoveFileQ records removal of file, like RemoveFileQ does.
+func (tx *Tx) RemoveFileQ(path string) {
+	tx.ops = append(tx.ops, txOp{kind: txRemoveFile, path: path})
+}
+
+// RemoveDirQ records removal of directory with its content, like RemoveDirQ does.
+func (tx *Tx) RemoveDirQ(path string) {
+	tx.ops = append(tx.ops, txOp{kind: txRemoveDir, path: path})
+}
+
+// RemoveEmptyDir records removal of empty directory, like RemoveEmptyDir does.
+func (tx *Tx) RemoveEmptyDir(path string) {
+	tx.ops = append(tx.ops, txOp{kind: txRemoveEmptyDir, path: path})
+}
+
+// Plan returns actions which Apply would take.
+func (tx *Tx) Plan() Plan {
+	var plan Plan
+	for _, op := range tx.ops[tx.started:] {
+		switch op.kind {
+		case txCreateDir:
+			plan = append(plan, PlanAction{Op: PlanMkdir, Path: op.path})
+		case txCreateFile:
+			plan = append(plan, PlanAction{Op: PlanWrite, Path: op.path, Bytes: contentSize(op.content)})
+		case txRename:
+			size, _ := treeSize(op.path)
+			plan = append(plan, PlanAction{Op: PlanRename, Path: op.path, Target: op.target, Bytes: size})
+		case txCopyFile:
+			size, _ := treeSize(op.path)
+			plan = append(plan, PlanAction{Op: PlanCopy, Path: op.path, Target: op.target, Bytes: size})
+		case txRemoveFile, txRemoveDir, txRemoveEmptyDir:
+			size, _ := treeSize(op.path)
+			plan = append(plan, PlanAction{Op: PlanRemove, Path: op.path, Bytes: size})
+		}
+	}
+	return plan
+}
+
+// Apply runs recorded operations in order.
+// If an operation fails, it's rolled back with completed operations
+// in reverse order and error is returned.
+// Operations recorded after Apply can be applied by next Apply call.
+func (tx *Tx) Apply() error {
+	if tx.closed {
+		return fmt.Errorf("transaction is already finished")
+	}
+	if tx.Journal == "" && tx.started < len(tx.ops) {
+		if err := tx.createJournal(); err != nil {
+			return err
+		}
+	}
+
+	for tx.started < len(tx.ops) {
+		i := tx.started
+		if err := tx.apply(&tx.ops[i]); err != nil {
+			err = fmt.Errorf("operation %v failed: %w", i+1, err)
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				return errors.Join(err, rollbackErr)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply checks operation, writes it to journal log and runs it.
+// Operation is counted as started once it's written,
+// so it's rolled back even if it fails part-way.
+func (tx *Tx) apply(op *txOp) error {
+	if err := checkTxOp(op); err != nil {
+		return err
+	}
+
+	if op.kind == txRemoveFile || op.kind == txRemoveDir {
+		op.backup = filepath.Join(tx.Journal, strconv.Itoa(tx.started))
+	}
+	if err := tx.record(txRecord{
+		Op:     txOpNames[op.kind],
+		Path:   op.path,
+		Target: op.target,
+		Backup: op.backup,
+		Mode:   op.mode,
+	}); err != nil {
+		return err
+	}
+	tx.started++
+
+	if err := runTxOp(op); err != nil {
+		return err
+	}
+	return tx.recordCreated(op)
+}
+
+// runTxOp runs operation.
+func runTxOp(op *txOp) error {
+	switch op.kind {
+	case txCreateDir:
+		return CreateDir(op.path)
+	case txCreateFile:
+		return CreateFileA(op.path, op.content)
+	case txRename:
+		if op.mode.IsDir() {
+			return MoveDir(op.path, op.target)
+		}
+		return RenameFile(op.path, op.target)
+	case txCopyFile:
+		return CopyFile(op.path, op.target)
+	case txRemoveFile, txRemoveDir:
+		return moveNoReplace(op.path, op.backup)
+	case txRemoveEmptyDir:
+		return RemoveEmptyDir(op.path)
+	}
+
+	return fmt.Errorf("unknown operation %v", op.kind)
+}
+
+// recordCreated remembers identity of file created by operation,
+// so rollback doesn't remove file which replaced it later.
+func (tx *Tx) recordCreated(op *txOp) error {
+	path := createdPath(*op)
+	if path == "" {
+		return nil
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	op.created = info
+
+	id, ok := fileID(info)
+	if !ok {
+		return nil
+	}
+	op.id = &id
+	return tx.record(txRecord{Op: txCreatedOp, Path: path, ID: op.id})
+}
+
+// createdPath returns path, which is created by operation.
+// Returns empty string, if operation doesn't create files.
+func createdPath(op txOp) string {
+	switch op.kind {
+	case txCreateDir, txCreateFile:
+		return op.path
+	case txCopyFile:
+		return op.target
+	}
+	return ""
+}
+
+// checkTxOp returns error, if operation can't be run.
+// Paths created by operation must not exist,
+// so undoing it never removes data which existed before.
+func checkTxOp(op *txOp) error {
+	switch op.kind {
+	case txCreateDir, txCreateFile:
+		if _, err := os.Lstat(op.path); err == nil {
+			return fmt.Errorf("file %v already exists", op.path)
+		}
+	case txRename, txCopyFile:
+		if _, err := os.Lstat(op.target); err == nil {
+			return fmt.Errorf("file %v already exists", op.target)
+		}
+	case txRemoveFile:
+		info, err := os.Lstat(op.path)
+		if err != nil {
+			return fmt.Errorf("file %v does not exist", op.path)
+		}
+		if !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+			return fmt.Errorf("%v is not a file", op.path)
+		}
+	case txRemoveDir:
+		if err := CheckRemove(op.path, DefaultRemoveGuard); err != nil {
+			return err
+		}
+		if !IsDirExists(op.path) {
+			return fmt.Errorf("dir doesn't exists: (%v)", op.path)
+		}
+	case txRemoveEmptyDir:
+		info, err := os.Stat(op.path)
+		if err != nil {
+			return err
+		}
+		op.mode = info.Mode().Perm()
+	}
+
+	return nil
+}
+
+// record appends record to journal log and syncs it to disk.
+func (tx *Tx) record(record txRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(tx.Journal, txLogName), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(data, '\n'))
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// undoTxOp reverts single started operation.
+// Operation could be completed only partially or not at all,
+// so only changes which are present are reverted.
+func undoTxOp(op txOp) error {
+	switch op.kind {
+	case txCreateDir, txCreateFile, txCopyFile:
+		return removeCreated(op)
+	case txRename:
+		if _, err := os.Lstat(op.target); os.IsNotExist(err) {
+			return nil
+		}
+		return os.Rename(op.target, op.path)
+	case txRemoveFile, txRemoveDir:
+		if _, err := os.Lstat(op.backup); os.IsNotExist(err) {
+			return nil
+		}
+		// Backup is complete, but source could be removed partially.
+		if err := os.RemoveAll(op.path); err != nil {
+			return err
+		}
+		return moveNoReplace(op.backup, op.path)
+	case txRemoveEmptyDir:
+		if _, err := os.Lstat(op.path); err == nil {
+			return nil
+		}
+		return os.Mkdir(op.path, op.mode)
+	}
+
+	return nil
+}
+
+// removeCreated removes file or empty directory created by operation.
+// If it was replaced by other file after operation, it's kept.
+// If operation failed before its file was recorded,
+// path is removed, because it didn't exist before operation.
+func removeCreated(op txOp) error {
+	path := createdPath(op)
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case op.created != nil && !os.SameFile(info, op.created):
+		return nil
+	case op.created == nil && op.id != nil:
+		if id, ok := fileID(info); ok && id != *op.id {
+			return nil
+		}
+	}
+
+	return os.Remove(path)
+}
+
+// Rollback reverts started operations in reverse order
+// and removes journal directory.
+// Operations which couldn't be reverted are reported by error,
+// then journal is kept so it can be recovered by RecoverTx.
+func (tx *Tx) Rollback() error {
+	if tx.closed {
+		return nil
+	}
+
+	tx.closed = true
+	err := undoTxOps(tx.ops[:tx.started])
+	tx.started = 0
+	if err != nil || tx.Journal == "" {
+		return err
+	}
+
+	return os.RemoveAll(tx.Journal)
+}
+
+// undoTxOps reverts operations in reverse order.
+func undoTxOps(ops []txOp) error {
+	var errs []error
+	for i := len(ops) - 1; i >= 0; i-- {
+		if err := undoTxOp(ops[i]); err != nil {
+			errs = append(errs, fmt.Errorf("rollback of operation %v: %w", i+1, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Commit finishes transaction and discards journal with backups.
+// Operations which weren't applied are dropped.
+func (tx *Tx) Commit() error {
+	if tx.closed {
+		return fmt.Errorf("transaction is already finished")
+	}
+
+	tx.closed = true
+	if tx.Journal == "" {
+		return nil
+	}
+	if err := tx.record(txRecord{Commit: true}); err != nil {
+		return err
+	}
+	return os.RemoveAll(tx.Journal)
+}
+
+// FindTxJournals returns journal directories left inside dir
+// by transactions which were neither committed nor rolled back.
+func FindTxJournals(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, txJournalPattern+"*"))
+}
+
+// RecoverTx rolls back transaction interrupted by crash
+// using its journal directory, then removes the journal.
+// If transaction was committed, journal is only removed.
+func RecoverTx(journal string) error {
+	file, err := os.Open(filepath.Join(journal, txLogName))
+	if os.IsNotExist(err) {
+		// Nothing was started.
+		return os.RemoveAll(journal)
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var ops []txOp
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record txRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// Last record could be written partially.
+			break
+		}
+		if record.Commit {
+			return os.RemoveAll(journal)
+		}
+		if record.Op == txCreatedOp {
+			if len(ops) > 0 {
+				ops[len(ops)-1].id = record.ID
+			}
+			continue
+		}
+
+		kind := slices.Index(txOpNames, record.Op)
+		if kind < 0 {
+			return fmt.Errorf("unknown operation %v in journal %v", record.Op, journal)
+		}
+		ops = append(ops, txOp{
+			kind:   txOpKind(kind),
+			path:   record.Path,
+			target: record.Target,
+			backup: record.Backup,
+			mode:   record.Mode,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if err := undoTxOps(ops); err != nil {
+		return err
+	}
+	return os.RemoveAll(journal)
+}
@@ -0,0 +1,222 @@
+package fs_utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTxCommit(t *testing.T) {
+	tempDir := t.TempDir()
+	oldDir := filepath.Join(tempDir, "old")
+	newDir := filepath.Join(tempDir, "new")
+	os.Mkdir(oldDir, os.ModePerm)
+	os.WriteFile(filepath.Join(oldDir, "test.txt"), []byte("test"), 0644)
+
+	tx, err := BeginTx(tempDir)
+	if err != nil {
+		t.Fatalf("expected to begin transaction, error: %v", err)
+	}
+
+	tx.CreateDir(newDir)
+	tx.CreateFileW(filepath.Join(newDir, "a.txt"), FileLines{"a"})
+	tx.CopyFile(filepath.Join(oldDir, "test.txt"), filepath.Join(newDir, "b.txt"))
+	tx.RemoveDirQ(oldDir)
+
+	if plan := tx.Plan(); len(plan) != 4 {
+		t.Errorf("expected 4 planned actions, got: %v", plan)
+	}
+
+	// Test applying operations
+	if err := tx.Apply(); err != nil {
+		t.Fatalf("expected to apply transaction, error: %v", err)
+	}
+
+	if !IsFileExists(filepath.Join(newDir, "b.txt")) || IsDirExists(oldDir) {
+		t.Errorf("expected operations to be applied")
+	}
+	if !IsDirExists(tx.Journal) {
+		t.Errorf("expected journal to exist before commit: %v", tx.Journal)
+	}
+
+	// Test committing
+	if err := tx.Commit(); err != nil {
+		t.Errorf("expected to commit transaction, error: %v", err)
+	}
+	if IsDirExists(tx.Journal) {
+		t.Errorf("expected journal to be removed: %v", tx.Journal)
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	tempDir := t.TempDir()
+	newDir := filepath.Join(tempDir, "new")
+	removed := filepath.Join(tempDir, "removed.txt")
+	renamed := filepath.Join(tempDir, "renamed.txt")
+	existing := filepath.Join(tempDir, "existing.txt")
+	os.WriteFile(removed, []byte("removed"), 0644)
+	os.WriteFile(renamed, []byte("renamed"), 0644)
+	os.WriteFile(existing, []byte("existing"), 0644)
+
+	tx, err := BeginTx(tempDir)
+	if err != nil {
+		t.Fatalf("expected to begin transaction, error: %v", err)
+	}
+
+	tx.CreateDir(newDir)
+	tx.CreateFileW(filepath.Join(newDir, "a.txt"), FileLines{"a"})
+	tx.RemoveFileQ(removed)
+	tx.RenameFile(renamed, filepath.Join(newDir, "renamed.txt"))
+	// Fails, because destination exists
+	tx.CopyFile(filepath.Join(newDir, "renamed.txt"), existing)
+
+	// Test rollback on failure
+	err = tx.Apply()
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected error for existing destination, got: %v", err)
+	}
+
+	if IsDirExists(newDir) {
+		t.Errorf("expected created directory to be removed: %v", newDir)
+	}
+	content, err := os.ReadFile(removed)
+	if err != nil || string(content) != "removed" {
+		t.Errorf("expected removed file to be restored, got: %q, error: %v", content, err)
+	}
+	if !IsFileExists(renamed) {
+		t.Errorf("expected renamed file to be renamed back: %v", renamed)
+	}
+	if IsDirExists(tx.Journal) {
+		t.Errorf("expected journal to be removed after rollback: %v", tx.Journal)
+	}
+	if content, _ := os.ReadFile(existing); string(content) != "existing" {
+		t.Errorf("expected existing file to be kept, got: %q", content)
+	}
+}
+
+func TestTxPartialFailure(t *testing.T) {
+	tempDir := t.TempDir()
+	source := filepath.Join(tempDir, "source")
+	destination := filepath.Join(tempDir, "destination.txt")
+	os.Mkdir(source, os.ModePerm)
+
+	tx, err := BeginTx("")
+	if err != nil {
+		t.Fatalf("expected to begin transaction, error: %v", err)
+	}
+
+	// Destination is created, but reading directory fails
+	tx.CopyFile(source, destination)
+	if err := tx.Apply(); err == nil {
+		t.Fatalf("expected error when copying directory")
+	}
+
+	if IsFileExists(destination) {
+		t.Errorf("expected partially copied file to be removed: %v", destination)
+	}
+	if filepath.Dir(tx.Journal) != tempDir {
+		t.Errorf("expected journal next to first path, got: %v", tx.Journal)
+	}
+}
+
+func TestTxRemoveFileDir(t *testing.T) {
+	tempDir := t.TempDir()
+	subdir := filepath.Join(tempDir, "subdir")
+	os.Mkdir(subdir, os.ModePerm)
+	os.WriteFile(filepath.Join(subdir, "test.txt"), []byte("test"), 0644)
+
+	tx, err := BeginTx(tempDir)
+	if err != nil {
+		t.Fatalf("expected to begin transaction, error: %v", err)
+	}
+
+	tx.RemoveFileQ(subdir)
+	if err := tx.Apply(); err == nil {
+		t.Errorf("expected error when removing directory as file")
+	}
+	if !IsFileExists(filepath.Join(subdir, "test.txt")) {
+		t.Errorf("expected directory to be kept: %v", subdir)
+	}
+}
+
+func TestRecoverTx(t *testing.T) {
+	tempDir := t.TempDir()
+	removed := filepath.Join(tempDir, "removed.txt")
+	created := filepath.Join(tempDir, "created.txt")
+	os.WriteFile(removed, []byte("removed"), 0644)
+
+	tx, err := BeginTx(tempDir)
+	if err != nil {
+		t.Fatalf("expected to begin transaction, error: %v", err)
+	}
+
+	tx.RemoveFileQ(removed)
+	tx.CreateFileW(created, FileLines{"created"})
+	if err := tx.Apply(); err != nil {
+		t.Fatalf("expected to apply transaction, error: %v", err)
+	}
+
+	// Transaction is interrupted before commit
+	journals, err := FindTxJournals(tempDir)
+	if err != nil || len(journals) != 1 || journals[0] != tx.Journal {
+		t.Fatalf("expected to find journal %v, got: %v, error: %v", tx.Journal, journals, err)
+	}
+
+	// Test recovering
+	if err := RecoverTx(journals[0]); err != nil {
+		t.Fatalf("expected to recover transaction, error: %v", err)
+	}
+	if content, _ := os.ReadFile(removed); string(content) != "removed" {
+		t.Errorf("expected removed file to be restored, got: %q", content)
+	}
+	if IsFileExists(created) {
+		t.Errorf("expected created file to be removed: %v", created)
+	}
+	if IsDirExists(tx.Journal) {
+		t.Errorf("expected journal to be removed: %v", tx.Journal)
+	}
+}
+
+// Test rollback keeps file, which replaced created one
+func TestTxRollbackReplaced(t *testing.T) {
+	tempDir := t.TempDir()
+	created := filepath.Join(tempDir, "created.txt")
+	replacement := filepath.Join(tempDir, "replacement.txt")
+
+	tx, err := BeginTx(tempDir)
+	if err != nil {
+		t.Fatalf("expected to begin transaction, error: %v", err)
+	}
+
+	tx.CreateFileW(created, FileLines{"created"})
+	if err := tx.Apply(); err != nil {
+		t.Fatalf("expected to apply transaction, error: %v", err)
+	}
+
+	os.WriteFile(replacement, []byte("replaced"), 0644)
+	os.Rename(replacement, created)
+
+	// Test recovering by journal
+	if err := RecoverTx(tx.Journal); err != nil {
+		t.Fatalf("expected to recover transaction, error: %v", err)
+	}
+	if content, _ := os.ReadFile(created); string(content) != "replaced" {
+		t.Errorf("expected replacing file to be kept, got: %q", content)
+	}
+
+	// Test rolling back in process
+	os.Remove(created)
+	tx, _ = BeginTx(tempDir)
+	tx.CreateFileW(created, FileLines{"created"})
+	tx.Apply()
+	os.WriteFile(replacement, []byte("replaced"), 0644)
+	os.Rename(replacement, created)
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("expected to roll back transaction, error: %v", err)
+	}
+	if content, _ := os.ReadFile(created); string(content) != "replaced" {
+		t.Errorf("expected replacing file to be kept, got: %q", content)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !plan9 && !windows
+
+package fs_utils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// isCrossDevice reports whether rename failed,
+// because paths are on different filesystems.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV)
+}
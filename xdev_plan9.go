@@ -0,0 +1,9 @@
+package fs_utils
+
+// isCrossDevice reports whether rename failed,
+// because paths are on different filesystems.
+// Rename on plan9 only changes name inside of directory,
+// so it's never attempted across filesystems.
+func isCrossDevice(err error) bool {
+	return false
+}
@@ -0,0 +1,15 @@
+package fs_utils
+
+import (
+	"errors"
+	"syscall"
+)
+
+// _ERROR_NOT_SAME_DEVICE is returned by MoveFileEx across volumes.
+const _ERROR_NOT_SAME_DEVICE = syscall.Errno(17)
+
+// isCrossDevice reports whether rename failed,
+// because paths are on different volumes.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, _ERROR_NOT_SAME_DEVICE) || errors.Is(err, syscall.EXDEV)
+}